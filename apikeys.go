@@ -0,0 +1,255 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const maxAPIKeysPerUser = 20
+
+var errInvalidAPIKey = errors.New("invalid api key")
+
+// apiKeyResponse never includes the key itself. It is only returned once,
+// from createAPIKey.
+type apiKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Key        string     `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(key database.ApiKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+	}
+	if key.LastUsedAt.Valid {
+		resp.LastUsedAt = &key.LastUsedAt.Time
+	}
+	return resp
+}
+
+// authenticate accepts either "Bearer <jwt>" or "ApiKey <key>" and returns
+// the caller's claims. Handlers still check scopes themselves.
+func authenticate(r *http.Request) (claims auth.Claims, err error) {
+	ctx, span := tracing.Start(r.Context(), "auth.Authenticate")
+	defer func() {
+		span.SetAttributes(attribute.String("auth.token_type", string(claims.Type)))
+		tracing.End(span, err)
+	}()
+	r = r.WithContext(ctx)
+	if strings.HasPrefix(r.Header.Get("Authorization"), "ApiKey ") {
+		key, err := auth.GetAPIKey(r.Header)
+		if err != nil {
+			return auth.Claims{}, err
+		}
+		return authenticateAPIKey(r, key)
+	}
+	//the middleware already checked the access token
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok && info.claims != nil {
+		return *info.claims, nil
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	return Cfg.keys.ValidateJWT(token)
+}
+
+func authenticateAPIKey(r *http.Request, key string) (auth.Claims, error) {
+	if !auth.IsAPIKey(key) {
+		return auth.Claims{}, errInvalidAPIKey
+	}
+	apiKey, err := Cfg.db.GetAPIKeyByHash(r.Context(), auth.HashToken(key))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logErrorf(r.Context(), "Error looking up api key: %v", err)
+		}
+		return auth.Claims{}, errInvalidAPIKey
+	}
+	//at most one write a minute per key, see TouchAPIKey
+	if err := Cfg.db.TouchAPIKey(r.Context(), apiKey.ID); err != nil {
+		logErrorf(r.Context(), "Error updating last use of api key %q: %v", apiKey.ID, err)
+	}
+	setRequestUser(r.Context(), apiKey.UserID)
+	return auth.Claims{
+		ID:     apiKey.ID.String(),
+		UserID: apiKey.UserID,
+		Type:   auth.TokenTypeAPIKey,
+		Scopes: apiKey.Scopes,
+	}, nil
+}
+
+func createAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token, api keys can't mint more keys
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	req := struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		http.Error(w, `{"error":"Name must be between 1 and 100 characters"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		http.Error(w, `{"error":"At least one scope is required"}`, http.StatusBadRequest)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(auth.APIKeyScopes, scope) {
+			http.Error(w, `{"error":"API keys can only have the read and write scopes"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+	existing, err := Cfg.db.ListAPIKeys(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error listing api keys for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to create api key"}`, http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxAPIKeysPerUser {
+		http.Error(w, `{"error":"Too many api keys, revoke one first"}`, http.StatusConflict)
+		return
+	}
+	key, prefix, hash, err := auth.MakeAPIKey()
+	if err != nil {
+		logErrorf(r.Context(), "Error generating api key: %v", err)
+		http.Error(w, `{"error":"Failed to create api key"}`, http.StatusInternalServerError)
+		return
+	}
+	apiKey, err := Cfg.db.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		UserID:  user_id,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  req.Scopes,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error saving api key for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to create api key"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := newAPIKeyResponse(apiKey)
+	resp.Key = key
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonResp)
+}
+
+func listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	keys, err := Cfg.db.ListAPIKeys(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error listing api keys for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to list api keys"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		resp = append(resp, newAPIKeyResponse(key))
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+func revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid api key id"}`, http.StatusBadRequest)
+		return
+	}
+	rows, err := Cfg.db.RevokeAPIKey(r.Context(), database.RevokeAPIKeyParams{
+		ID:     id,
+		UserID: user_id,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error revoking api key %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to revoke api key"}`, http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, `{"error":"API key not found"}`, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
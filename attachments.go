@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAttachmentSize    = 5 << 20
+	maxAttachmentsPerYap = 4
+)
+
+// allowedMediaTypes are checked against the sniffed content type, never the
+// one the client claims.
+var allowedMediaTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type attachmentResponse struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+}
+
+func newAttachmentResponse(a database.Attachment) attachmentResponse {
+	return attachmentResponse{
+		ID:          a.ID,
+		URL:         "/media/" + a.BlobKey,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+	}
+}
+
+func uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	user_id := claims.UserID
+	yap_id, err := uuid.Parse(r.PathValue("yapId"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	//only the author can attach media to a yap
+	yap, err := Cfg.db.GetYapByID(r.Context(), yap_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Yap not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to upload attachment"}`, http.StatusInternalServerError)
+		return
+	}
+	if yap.UserID != user_id {
+		http.Error(w, `{"error":"This is not your yap"}`, http.StatusForbidden)
+		return
+	}
+	count, err := Cfg.db.CountAttachmentsForYap(r.Context(), yap_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error counting attachments for yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to upload attachment"}`, http.StatusInternalServerError)
+		return
+	}
+	if count >= maxAttachmentsPerYap {
+		http.Error(w, `{"error":"Yap already has the maximum number of attachments"}`, http.StatusConflict)
+		return
+	}
+	//leave some room on top of the file for the multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+(1<<20))
+	file, header, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, `{"error":"Attachment is too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Expected a multipart upload with a \"file\" field"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	if header.Size > maxAttachmentSize {
+		http.Error(w, `{"error":"Attachment is too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+	//sniff the real content type from the first 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		http.Error(w, `{"error":"Could not read attachment"}`, http.StatusBadRequest)
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !allowedMediaTypes[contentType] {
+		http.Error(w, `{"error":"Unsupported media type `+contentType+`"}`, http.StatusUnsupportedMediaType)
+		return
+	}
+	key, size, err := Cfg.blobs.Put(r.Context(), io.MultiReader(bytes.NewReader(head[:n]), file))
+	if err != nil {
+		logErrorf(r.Context(), "Error storing attachment for yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to upload attachment"}`, http.StatusInternalServerError)
+		return
+	}
+	attachment, err := Cfg.db.NewAttachment(r.Context(), database.NewAttachmentParams{
+		YapID:       yap_id,
+		BlobKey:     key,
+		ContentType: contentType,
+		Size:        size,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error recording attachment for yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to upload attachment"}`, http.StatusInternalServerError)
+		return
+	}
+	jsonResp, err := json.Marshal(newAttachmentResponse(attachment))
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonResp)
+}
+
+func serveMedia(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if !storage.ValidKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+	//only blobs that are attached to a yap are served
+	attachment, err := Cfg.db.GetAttachmentByBlobKey(r.Context(), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching attachment %q: %v", key, err)
+		http.Error(w, "Failed to fetch media", http.StatusInternalServerError)
+		return
+	}
+	f, err := Cfg.blobs.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error opening blob %q: %v", key, err)
+		http.Error(w, "Failed to fetch media", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	//blobs are content addressed, so a key's bytes never change
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+key+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", attachment.CreatedAt, f)
+}
+
+// withAttachments fills in the attachments for each yap with a single query.
+func withAttachments(ctx context.Context, yaps []yapResponse) error {
+	if len(yaps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(yaps))
+	for _, yap := range yaps {
+		ids = append(ids, yap.ID)
+	}
+	attachments, err := Cfg.db.GetAttachmentsForYaps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byYap := make(map[uuid.UUID][]attachmentResponse)
+	for _, a := range attachments {
+		byYap[a.YapID] = append(byYap[a.YapID], newAttachmentResponse(a))
+	}
+	for i := range yaps {
+		yaps[i].Attachments = byYap[yaps[i].ID]
+		if yaps[i].Attachments == nil {
+			yaps[i].Attachments = []attachmentResponse{}
+		}
+	}
+	return nil
+}
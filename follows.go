@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+func follow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	user_id := claims.UserID
+	followee_id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	if followee_id == user_id {
+		http.Error(w, `{"error":"You cannot follow yourself"}`, http.StatusBadRequest)
+		return
+	}
+	//make sure the followee exists before inserting
+	_, err = Cfg.db.GetUserByID(r.Context(), followee_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching user %q: %v", followee_id, err)
+		http.Error(w, `{"error":"Failed to follow user"}`, http.StatusInternalServerError)
+		return
+	}
+	params := database.FollowUserParams{
+		FollowerID: user_id,
+		FolloweeID: followee_id,
+	}
+	if err := Cfg.db.FollowUser(r.Context(), params); err != nil {
+		logErrorf(r.Context(), "Error following user %q: %v", followee_id, err)
+		http.Error(w, `{"error":"Failed to follow user"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func unfollow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	user_id := claims.UserID
+	followee_id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	params := database.UnfollowUserParams{
+		FollowerID: user_id,
+		FolloweeID: followee_id,
+	}
+	if err := Cfg.db.UnfollowUser(r.Context(), params); err != nil {
+		logErrorf(r.Context(), "Error unfollowing user %q: %v", followee_id, err)
+		http.Error(w, `{"error":"Failed to unfollow user"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func timeline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeRead) {
+		return
+	}
+	user_id := claims.UserID
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	//newest yaps from everyone the user follows
+	yaps, err := Cfg.db.GetTimeline(r.Context(), database.GetTimelineParams{
+		FollowerID:      user_id,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching timeline for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to fetch timeline"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := newYapPage(newYapResponses(yaps), page)
+	if err := hydrateYaps(r.Context(), user_id, resp.Yaps); err != nil {
+		logErrorf(r.Context(), "Error hydrating yaps: %v", err)
+		http.Error(w, `{"error":"Failed to fetch yaps"}`, http.StatusInternalServerError)
+		return
+	}
+	yapsJSON, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(yapsJSON)
+}
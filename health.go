@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/health"
+	"github.com/F0RG-2142/chirpy-proj/internal/storage"
+)
+
+// schemaVersion is the goose version of the newest migration in sql/schema.
+// Bump it with every migration, TestSchemaVersion fails until it is.
+const schemaVersion = 19
+
+const (
+	healthCacheTTL     = 5 * time.Second
+	healthCheckTimeout = 2 * time.Second
+)
+
+// newHealthChecker returns the checks behind /readyz.
+func newHealthChecker(db *sql.DB, blobs storage.BlobStore) *health.Checker {
+	checker := health.New(healthCacheTTL, healthCheckTimeout)
+	checker.Add("database", db.PingContext)
+	checker.Add("migrations", func(ctx context.Context) error {
+		version, err := migrationVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		//a newer schema is fine, migrations run before a rollout finishes
+		if version < schemaVersion {
+			return fmt.Errorf("database is at migration %d, expected %d", version, schemaVersion)
+		}
+		return nil
+	})
+	checker.Add("blobs", func(ctx context.Context) error {
+		//blobs are content addressed, so this only ever stores one
+		_, _, err := blobs.Put(ctx, strings.NewReader("yappy readiness probe\n"))
+		return err
+	})
+	return checker
+}
+
+// migrationVersion works out the current version the way goose does. Older
+// goose versions record a down migration as a new row rather than deleting
+// the row of the up migration, so the newest applied row that hasn't been
+// rolled back since wins.
+func migrationVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	rolledBack := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var applied bool
+		if err := rows.Scan(&version, &applied); err != nil {
+			return 0, err
+		}
+		if rolledBack[version] {
+			continue
+		}
+		if applied {
+			return version, nil
+		}
+		rolledBack[version] = true
+	}
+	return 0, rows.Err()
+}
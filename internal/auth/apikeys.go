@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every personal API key so they are easy to spot in
+// logs and secret scanners.
+const APIKeyPrefix = "yappy_"
+
+// apiKeyDisplayLength is how much of a key is kept in plain text so users
+// can tell their keys apart.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// APIKeyScopes are the scopes a personal API key may be granted. Keys can
+// never manage the account that owns them.
+var APIKeyScopes = []string{ScopeRead, ScopeWrite}
+
+// MakeAPIKey returns a new key to show the user once, a short prefix to
+// display afterwards, and the hash to store.
+func MakeAPIKey() (key, prefix, hash string, err error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + hex.EncodeToString(randomBytes)
+	return key, key[:apiKeyDisplayLength], HashToken(key), nil
+}
+
+// IsAPIKey reports whether s looks like a personal API key.
+func IsAPIKey(s string) bool {
+	return strings.HasPrefix(s, APIKeyPrefix) && len(s) == len(APIKeyPrefix)+64
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMakeAPIKey(t *testing.T) {
+	key, prefix, hash, err := MakeAPIKey()
+	if err != nil {
+		t.Fatalf("failed to make key: %v", err)
+	}
+	if !IsAPIKey(key) {
+		t.Errorf("expected %q to look like an api key", key)
+	}
+	if !strings.HasPrefix(key, prefix) || len(prefix) >= len(key) {
+		t.Errorf("expected %q to be a short prefix of %q", prefix, key)
+	}
+	if hash != HashToken(key) {
+		t.Errorf("expected hash %q, got %q", HashToken(key), hash)
+	}
+	other, _, _, err := MakeAPIKey()
+	if err != nil {
+		t.Fatalf("failed to make key: %v", err)
+	}
+	if other == key {
+		t.Error("expected two keys to differ")
+	}
+}
+
+func TestIsAPIKey(t *testing.T) {
+	key, _, _, _ := MakeAPIKey()
+	tests := []struct {
+		name     string
+		key      string
+		expected bool
+	}{
+		{name: "Valid", key: key, expected: true},
+		{name: "Truncated", key: key[:20], expected: false},
+		{name: "No Prefix", key: strings.TrimPrefix(key, APIKeyPrefix), expected: false},
+		{name: "JWT", key: "eyJhbGciOiJIUzI1NiJ9.e30.sig", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAPIKey(tt.key); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -35,6 +35,7 @@ func CheckPasswordHash(hash, password string) error {
 	return err
 }
 
+// MakeJWT signs an HS256 access token with the default scopes.
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
 	if tokenSecret == "" {
 		return "", fmt.Errorf("no secret entered")
@@ -42,12 +43,7 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	if len(tokenSecret) < 32 {
 		return "", fmt.Errorf("enter a secure secret(minimum 32 bytes)")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "yappy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newTokenClaims(TokenTypeAccess, userID, AccessAudience, DefaultScopes, expiresIn))
 	wt, err := token.SignedString([]byte(tokenSecret))
 	if err != nil {
 		return "", err
@@ -56,24 +52,13 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	parsedToken, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (any, error) {
+	_, claims, err := parseToken(tokenString, TokenTypeAccess, AccessAudience, func(token *jwt.Token) (any, error) {
 		return []byte(tokenSecret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return uuid.Nil, err
 	}
-
-	claims, ok := parsedToken.Claims.(*jwt.RegisteredClaims)
-	if !ok || !parsedToken.Valid {
-		return uuid.Nil, jwt.ErrTokenMalformed
-	}
-
-	userID, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	return userID, nil
+	return claims.UserID, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestAccessTokenClaims(t *testing.T) {
+	userID := uuid.New()
+	key, _ := newEd25519Key(t)
+	ring, _ := NewKeyring(key)
+
+	token, err := ring.MakeJWT(userID, []string{ScopeRead}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	claims, err := ring.ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.UserID != userID || claims.Type != TokenTypeAccess {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if !slices.Equal(claims.Audience, []string{AccessAudience}) {
+		t.Errorf("expected audience %q, got %v", AccessAudience, claims.Audience)
+	}
+	if !claims.HasScope(ScopeRead) || claims.HasScope(ScopeWrite) {
+		t.Errorf("expected only the read scope, got %v", claims.Scopes)
+	}
+}
+
+func TestValidateJWTRejectsOtherTokens(t *testing.T) {
+	userID := uuid.New()
+	key, _ := newEd25519Key(t)
+	ring, _ := NewKeyring(key)
+
+	sign := func(claims *tokenClaims) string {
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		s, err := token.SignedString(key.signer)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return s
+	}
+	wrongType := newTokenClaims(TokenTypeMFA, userID, AccessAudience, nil, time.Hour)
+	wrongAudience := newTokenClaims(TokenTypeAccess, userID, "some-other-api", DefaultScopes, time.Hour)
+	wrongIssuer := newTokenClaims(TokenTypeAccess, userID, AccessAudience, DefaultScopes, time.Hour)
+	wrongIssuer.Issuer = "not-yappy"
+	noExpiry := newTokenClaims(TokenTypeAccess, userID, AccessAudience, DefaultScopes, time.Hour)
+	noExpiry.ExpiresAt = nil
+
+	tests := []struct {
+		name   string
+		claims *tokenClaims
+	}{
+		{name: "Wrong Type", claims: wrongType},
+		{name: "Wrong Audience", claims: wrongAudience},
+		{name: "Wrong Issuer", claims: wrongIssuer},
+		{name: "No Expiry", claims: noExpiry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ring.ValidateJWT(sign(tt.claims)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestClientAccessToken(t *testing.T) {
+	key, _ := newEd25519Key(t)
+	ring, _ := NewKeyring(key)
+
+	token, err := ring.MakeClientJWT(uuid.New(), "client-123", OAuthScopes, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	claims, err := ring.ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.ClientID != "client-123" {
+		t.Errorf("expected client id %q, got %q", "client-123", claims.ClientID)
+	}
+	if claims.HasScope(ScopeAccount) {
+		t.Error("expected client token not to have the account scope")
+	}
+}
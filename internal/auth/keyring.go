@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Key is one JWT signing key. Keys loaded from a public key file can only
+// verify tokens.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// NotAfter, if set, is when the key stops verifying tokens.
+	NotAfter time.Time
+	signer   any
+	public   any
+}
+
+// CanSign reports whether the key holds private material.
+func (k *Key) CanSign() bool {
+	return k.signer != nil
+}
+
+// NewHMACKey wraps a shared secret as an HS256 key. HMAC keys have no kid
+// and are never published in the JWKS.
+func NewHMACKey(secret string) (*Key, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("enter a secure secret(minimum 32 bytes)")
+	}
+	return &Key{Method: jwt.SigningMethodHS256, signer: []byte(secret), public: []byte(secret)}, nil
+}
+
+// NewKey wraps an RSA or Ed25519 private or public key. The kid is the
+// RFC 7638 thumbprint of the public key so it is stable across restarts.
+func NewKey(key any) (*Key, error) {
+	k := &Key{}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.signer, k.public = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.public = jwt.SigningMethodRS256, key
+	case ed25519.PrivateKey:
+		k.Method, k.signer, k.public = jwt.SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.public = jwt.SigningMethodEdDSA, key
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if rsaKey, ok := k.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("rsa keys must be at least 2048 bits")
+	}
+	k.ID = k.thumbprint()
+	return k, nil
+}
+
+// LoadKey reads a PEM encoded PKCS#8 or PKCS#1 private key, or a PKIX
+// public key, from path.
+func LoadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	k, err := NewKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return k, nil
+}
+
+// Keyring signs access tokens with the current key and verifies them against
+// the current key and any previous ones, so keys can be rotated without
+// logging everyone out.
+type Keyring struct {
+	current *Key
+	keys    []*Key
+	byID    map[string]*Key
+	legacy  *Key
+}
+
+// NewKeyring builds a keyring that signs with current. Previous keys only
+// verify. At most one HMAC key may be given; it verifies tokens with no kid,
+// which is how tokens issued before asymmetric keys were configured look.
+func NewKeyring(current *Key, previous ...*Key) (*Keyring, error) {
+	if current == nil || !current.CanSign() {
+		return nil, errors.New("current key must be a private key")
+	}
+	k := &Keyring{current: current, byID: map[string]*Key{}}
+	for _, key := range append([]*Key{current}, previous...) {
+		if key.ID == "" {
+			if k.legacy != nil {
+				return nil, errors.New("only one HMAC key is allowed")
+			}
+			k.legacy = key
+			continue
+		}
+		if _, ok := k.byID[key.ID]; !ok {
+			k.keys = append(k.keys, key)
+		}
+		k.byID[key.ID] = key
+	}
+	return k, nil
+}
+
+// MakeJWT signs an access token carrying scopes with the current key.
+func (k *Keyring) MakeJWT(userID uuid.UUID, scopes []string, expiresIn time.Duration) (string, error) {
+	return k.MakeClientJWT(userID, "", scopes, expiresIn)
+}
+
+// MakeClientJWT is MakeJWT for a token issued to an OAuth client.
+func (k *Keyring) MakeClientJWT(userID uuid.UUID, clientID string, scopes []string, expiresIn time.Duration) (string, error) {
+	claims := newTokenClaims(TokenTypeAccess, userID, AccessAudience, scopes, expiresIn)
+	claims.ClientID = clientID
+	token := jwt.NewWithClaims(k.current.Method, claims)
+	if k.current.ID != "" {
+		token.Header["kid"] = k.current.ID
+	}
+	return token.SignedString(k.current.signer)
+}
+
+// ValidateJWT checks an access token against every key in the ring and
+// returns its claims. Handlers still have to check the scopes they need.
+func (k *Keyring) ValidateJWT(tokenString string) (Claims, error) {
+	_, claims, err := parseToken(tokenString, TokenTypeAccess, AccessAudience, k.keyfunc)
+	return claims, err
+}
+
+// keyfunc picks the verification key by kid and refuses any token whose alg
+// doesn't match that key, so an RSA public key can never be used as an HMAC
+// secret.
+func (k *Keyring) keyfunc(token *jwt.Token) (any, error) {
+	key := k.legacy
+	if kid, ok := token.Header["kid"].(string); ok {
+		key = k.byID[kid]
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key")
+	}
+	if !key.NotAfter.IsZero() && time.Now().After(key.NotAfter) {
+		return nil, fmt.Errorf("signing key is retired")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// JWK is the public half of a key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the body of /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys other services need to verify our tokens.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	return set
+}
+
+func (k *Key) jwk() JWK {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Use: "sig", Alg: k.Method.Alg(), Kid: k.ID, N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Use: "sig", Alg: k.Method.Alg(), Kid: k.ID, Crv: "Ed25519", X: b64(pub)}
+	}
+	return JWK{}
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint: the SHA-256 of the required
+// members in lexical order with no whitespace.
+func (k *Key) thumbprint() string {
+	jwk := k.jwk()
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newRSAKey(t *testing.T) *Key {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate rsa key: %v", err)
+	}
+	key, err := NewKey(priv)
+	if err != nil {
+		t.Fatalf("failed to wrap rsa key: %v", err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) (*Key, ed25519.PrivateKey) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+	key, err := NewKey(priv)
+	if err != nil {
+		t.Fatalf("failed to wrap ed25519 key: %v", err)
+	}
+	return key, priv
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	userID := uuid.New()
+	edKey, _ := newEd25519Key(t)
+	for _, key := range []*Key{newRSAKey(t), edKey} {
+		t.Run(key.Method.Alg(), func(t *testing.T) {
+			ring, err := NewKeyring(key)
+			if err != nil {
+				t.Fatalf("failed to make keyring: %v", err)
+			}
+			token, err := ring.MakeJWT(userID, DefaultScopes, time.Hour)
+			if err != nil {
+				t.Fatalf("failed to make token: %v", err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			if parsed.Header["kid"] != key.ID {
+				t.Errorf("expected kid %q, got %v", key.ID, parsed.Header["kid"])
+			}
+			claims, err := ring.ValidateJWT(token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claims.UserID != userID {
+				t.Errorf("expected user %v, got %v", userID, claims.UserID)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	userID := uuid.New()
+	tokenSecret := "test-secret-12345678901234567890123456789012"
+	oldKey := newRSAKey(t)
+	newKey, _ := newEd25519Key(t)
+	legacy, err := NewHMACKey(tokenSecret)
+	if err != nil {
+		t.Fatalf("failed to make hmac key: %v", err)
+	}
+
+	oldRing, _ := NewKeyring(oldKey)
+	oldToken, _ := oldRing.MakeJWT(userID, DefaultScopes, time.Hour)
+	legacyToken, _ := MakeJWT(userID, tokenSecret, time.Hour)
+
+	rotated, err := NewKeyring(newKey, oldKey, legacy)
+	if err != nil {
+		t.Fatalf("failed to make keyring: %v", err)
+	}
+	if _, err := rotated.ValidateJWT(oldToken); err != nil {
+		t.Errorf("expected token from previous key to validate: %v", err)
+	}
+	if _, err := rotated.ValidateJWT(legacyToken); err != nil {
+		t.Errorf("expected legacy hmac token to validate: %v", err)
+	}
+
+	//once the old key is dropped its tokens stop working
+	dropped, _ := NewKeyring(newKey)
+	if _, err := dropped.ValidateJWT(oldToken); err == nil {
+		t.Error("expected token from removed key to be rejected")
+	}
+	if _, err := dropped.ValidateJWT(legacyToken); err == nil {
+		t.Error("expected hmac token to be rejected without a legacy key")
+	}
+
+	//or once it is past its NotAfter
+	legacy.NotAfter = time.Now().Add(-time.Second)
+	retired, _ := NewKeyring(newKey, legacy)
+	if _, err := retired.ValidateJWT(legacyToken); err == nil {
+		t.Error("expected hmac token to be rejected after the key's NotAfter")
+	}
+}
+
+func TestKeyringRejectsAlgorithmConfusion(t *testing.T) {
+	key := newRSAKey(t)
+	ring, _ := NewKeyring(key)
+	//sign HS256 with the public key bytes and claim the rsa kid
+	pub, err := x509.MarshalPKIXPublicKey(key.public)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   uuid.New().String(),
+	})
+	token.Header["kid"] = key.ID
+	forged, err := token.SignedString(pub)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := ring.ValidateJWT(forged); err == nil {
+		t.Error("expected HS256 token with an rsa kid to be rejected")
+	}
+}
+
+func TestNewKeyringRequiresPrivateKey(t *testing.T) {
+	key, priv := newEd25519Key(t)
+	public, err := NewKey(priv.Public())
+	if err != nil {
+		t.Fatalf("failed to wrap public key: %v", err)
+	}
+	if public.ID != key.ID {
+		t.Errorf("expected public and private key to share kid %q, got %q", key.ID, public.ID)
+	}
+	if _, err := NewKeyring(public); err == nil {
+		t.Error("expected a public key to be refused as the signing key")
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	_, priv := newEd25519Key(t)
+	dir := t.TempDir()
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	privPath := filepath.Join(dir, "signing.pem")
+	os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	der, _ = x509.MarshalPKIXPublicKey(priv.Public())
+	pubPath := filepath.Join(dir, "previous.pem")
+	os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+
+	signing, err := LoadKey(privPath)
+	if err != nil {
+		t.Fatalf("failed to load private key: %v", err)
+	}
+	if !signing.CanSign() || signing.Method != jwt.SigningMethodEdDSA {
+		t.Errorf("unexpected key %+v", signing)
+	}
+	verifying, err := LoadKey(pubPath)
+	if err != nil {
+		t.Fatalf("failed to load public key: %v", err)
+	}
+	if verifying.CanSign() || verifying.ID != signing.ID {
+		t.Errorf("unexpected key %+v", verifying)
+	}
+	os.WriteFile(privPath, []byte("not a key"), 0o600)
+	if _, err := LoadKey(privPath); err == nil {
+		t.Error("expected garbage file to be rejected")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	current, _ := newEd25519Key(t)
+	previous := newRSAKey(t)
+	legacy, _ := NewHMACKey("test-secret-12345678901234567890123456789012")
+	ring, _ := NewKeyring(current, previous, legacy)
+
+	set := ring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 published keys, got %d", len(set.Keys))
+	}
+	if set.Keys[0].Kid != current.ID || set.Keys[0].Kty != "OKP" || set.Keys[0].Crv != "Ed25519" {
+		t.Errorf("unexpected current key %+v", set.Keys[0])
+	}
+	if set.Keys[1].Kid != previous.ID || set.Keys[1].Kty != "RSA" || set.Keys[1].E != "AQAB" {
+		t.Errorf("unexpected previous key %+v", set.Keys[1])
+	}
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// MakeMFAToken issues the short lived challenge token handed out by login
+// when the account has two-factor auth enabled. It proves the password was
+// correct and nothing else, so it is signed with its own key.
+func MakeMFAToken(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	if len(tokenSecret) < 32 {
+		return "", fmt.Errorf("enter a secure secret(minimum 32 bytes)")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newTokenClaims(TokenTypeMFA, userID, Issuer, nil, expiresIn))
+	return token.SignedString(purposeKey(tokenSecret, "mfa"))
+}
+
+func ValidateMFAToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	_, claims, err := parseToken(tokenString, TokenTypeMFA, Issuer, func(token *jwt.Token) (any, error) {
+		return purposeKey(tokenSecret, "mfa"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID, nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MakeRecoveryCodes returns n random single-use codes formatted as
+// xxxxx-xxxxx for the user to write down.
+func MakeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes a recovery code for storage. Case, spaces and
+// dashes are ignored so codes can be typed back however they were copied.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashToken(code)
+}
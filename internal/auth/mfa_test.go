@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMFAToken(t *testing.T) {
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	tokenSecret := "test-secret-12345678901234567890123456789012"
+
+	token, err := MakeMFAToken(userID, tokenSecret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	got, err := ValidateMFAToken(token, tokenSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != userID {
+		t.Errorf("expected user %v, got %v", userID, got)
+	}
+	//a challenge token must never work as an access token, or vice versa
+	if _, err := ValidateJWT(token, tokenSecret); err == nil {
+		t.Error("expected mfa token to be rejected as an access token")
+	}
+	accessToken, err := MakeJWT(userID, tokenSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make access token: %v", err)
+	}
+	if _, err := ValidateMFAToken(accessToken, tokenSecret); err == nil {
+		t.Error("expected access token to be rejected as an mfa token")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("failed to make codes: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	if HashRecoveryCode(typed) != HashRecoveryCode(codes[0]) {
+		t.Error("expected hash to ignore case, spaces and dashes")
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCLogin is what a single sign-on attempt has to remember between
+// sending the user to the provider and the provider sending them back.
+type OIDCLogin struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewOIDCLogin generates a random state, nonce and PKCE code_verifier.
+func NewOIDCLogin() (OIDCLogin, error) {
+	values := make([]string, 3)
+	for i := range values {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return OIDCLogin{}, err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	return OIDCLogin{State: values[0], Nonce: values[1], Verifier: values[2]}, nil
+}
+
+type oidcLoginClaims struct {
+	Type     TokenType `json:"typ"`
+	Nonce    string    `json:"nonce"`
+	Verifier string    `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// MakeOIDCLoginToken signs login so it can be kept in a cookie rather than
+// on the server. The state is the token's jti.
+func MakeOIDCLoginToken(login OIDCLogin, tokenSecret string, expiresIn time.Duration) (string, error) {
+	if len(tokenSecret) < 32 {
+		return "", fmt.Errorf("enter a secure secret(minimum 32 bytes)")
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &oidcLoginClaims{
+		Type:     TokenTypeOIDCLogin,
+		Nonce:    login.Nonce,
+		Verifier: login.Verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        login.State,
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Issuer},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		},
+	})
+	return token.SignedString(purposeKey(tokenSecret, "oidc-login"))
+}
+
+func ValidateOIDCLoginToken(tokenString, tokenSecret string) (OIDCLogin, error) {
+	raw := &oidcLoginClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, raw, func(token *jwt.Token) (any, error) {
+		return purposeKey(tokenSecret, "oidc-login"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(Issuer),
+		jwt.WithAudience(Issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	if raw.Type != TokenTypeOIDCLogin {
+		return OIDCLogin{}, fmt.Errorf("%w: expected %s, got %q", ErrWrongTokenType, TokenTypeOIDCLogin, raw.Type)
+	}
+	return OIDCLogin{State: raw.ID, Nonce: raw.Nonce, Verifier: raw.Verifier}, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOIDCLoginToken(t *testing.T) {
+	tokenSecret := "test-secret-12345678901234567890123456789012"
+
+	login, err := NewOIDCLogin()
+	if err != nil {
+		t.Fatalf("failed to make login: %v", err)
+	}
+	if !ValidCodeVerifier(login.Verifier) {
+		t.Errorf("invalid code verifier %q", login.Verifier)
+	}
+	token, err := MakeOIDCLoginToken(login, tokenSecret, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	got, err := ValidateOIDCLoginToken(token, tokenSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != login {
+		t.Errorf("expected %+v, got %+v", login, got)
+	}
+	if _, err := ValidateOIDCLoginToken(token, "wrong-secret-1234567890123456789012345678"); err == nil {
+		t.Error("expected token signed with another secret to be rejected")
+	}
+	expired, err := MakeOIDCLoginToken(login, tokenSecret, -time.Second)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	if _, err := ValidateOIDCLoginToken(expired, tokenSecret); err == nil {
+		t.Error("expected expired token to be rejected")
+	}
+	if _, err := ValidateJWT(token, tokenSecret); err == nil {
+		t.Error("expected login token to be rejected as an access token")
+	}
+}
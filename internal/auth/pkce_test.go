@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	//RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		expected  bool
+	}{
+		{name: "RFC Example", verifier: verifier, challenge: challenge, expected: true},
+		{name: "Wrong Verifier", verifier: strings.Replace(verifier, "d", "e", 1), challenge: challenge, expected: false},
+		{name: "Plain Method", verifier: verifier, challenge: verifier, expected: false},
+		{name: "Too Short", verifier: "abc", challenge: S256Challenge("abc"), expected: false},
+		{name: "Bad Characters", verifier: strings.Repeat("a", 42) + "!", challenge: S256Challenge(strings.Repeat("a", 42) + "!"), expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// MakePasswordResetToken returns a random reset token to mail to the user and
+// the hash to store in its place, so a leaked table can't be used to take
+// over accounts.
+func MakePasswordResetToken() (token, hash string, err error) {
+	token, err = MakeRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// HashToken is the lookup key for an opaque token stored server side.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package auth
+
+import "testing"
+
+func TestMakePasswordResetToken(t *testing.T) {
+	token, hash, err := MakePasswordResetToken()
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	if token == hash {
+		t.Error("expected the stored hash to differ from the token")
+	}
+	if HashToken(token) != hash {
+		t.Errorf("expected hash %q, got %q", hash, HashToken(token))
+	}
+	other, _, err := MakePasswordResetToken()
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	if other == token {
+		t.Error("expected two tokens to differ")
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// EmailVerificationClaims identify the address a verification link was sent
+// to. ID is the token's jti and is what makes the token single use.
+type EmailVerificationClaims struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Email  string
+}
+
+func MakeEmailVerificationToken(userID uuid.UUID, email, tokenSecret string, expiresIn time.Duration) (string, uuid.UUID, error) {
+	if len(tokenSecret) < 32 {
+		return "", uuid.Nil, fmt.Errorf("enter a secure secret(minimum 32 bytes)")
+	}
+	id := uuid.New()
+	claims := newTokenClaims(TokenTypeEmailVerification, userID, Issuer, nil, expiresIn)
+	claims.ID = id.String()
+	claims.Email = email
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	wt, err := token.SignedString(purposeKey(tokenSecret, "email-verification"))
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	return wt, id, nil
+}
+
+func ValidateEmailVerificationToken(tokenString, tokenSecret string) (EmailVerificationClaims, error) {
+	raw, claims, err := parseToken(tokenString, TokenTypeEmailVerification, Issuer, func(token *jwt.Token) (any, error) {
+		return purposeKey(tokenSecret, "email-verification"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return EmailVerificationClaims{}, err
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return EmailVerificationClaims{}, err
+	}
+	return EmailVerificationClaims{ID: id, UserID: claims.UserID, Email: raw.Email}, nil
+}
+
+// purposeKey derives a separate HMAC key from the JWT secret for each kind
+// of special purpose token, so none of them can ever pass ValidateJWT as an
+// access token.
+func purposeKey(tokenSecret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
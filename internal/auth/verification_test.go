@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEmailVerificationToken(t *testing.T) {
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	tokenSecret := "test-secret-12345678901234567890123456789012"
+
+	token, id, err := MakeEmailVerificationToken(userID, "user@example.com", tokenSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	claims, err := ValidateEmailVerificationToken(token, tokenSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.ID != id || claims.UserID != userID || claims.Email != "user@example.com" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	expired, _, err := MakeEmailVerificationToken(userID, "user@example.com", tokenSecret, -time.Second)
+	if err != nil {
+		t.Fatalf("failed to make expired token: %v", err)
+	}
+	accessToken, err := MakeJWT(userID, tokenSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make access token: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		tokenString string
+		tokenSecret string
+	}{
+		{
+			name:        "Expired Token",
+			tokenString: expired,
+			tokenSecret: tokenSecret,
+		},
+		{
+			name:        "Wrong Secret",
+			tokenString: token,
+			tokenSecret: "wrong-secret-1234567890123456789012345678",
+		},
+		{
+			name:        "Access Token",
+			tokenString: accessToken,
+			tokenSecret: tokenSecret,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ValidateEmailVerificationToken(tt.tokenString, tt.tokenSecret); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+
+	t.Run("Not An Access Token", func(t *testing.T) {
+		if _, err := ValidateJWT(token, tokenSecret); err == nil {
+			t.Error("expected a verification token to be rejected as an access token")
+		}
+	})
+}
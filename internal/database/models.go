@@ -11,13 +11,131 @@ import (
 	"github.com/google/uuid"
 )
 
-type RefreshToken struct {
-	Token     string
+type ApiKey struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type Attachment struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	YapID       uuid.UUID
+	BlobKey     string
+	ContentType string
+	Size        int64
+}
+
+type EmailVerification struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
 	UserID    uuid.UUID
+	Email     string
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	YapID     uuid.UUID
+	CreatedAt time.Time
+}
+
+type LoginFailure struct {
+	Kind          string
+	Key           string
+	Failures      int32
+	FirstFailedAt time.Time
+	LastFailedAt  time.Time
+	BlockedUntil  time.Time
+}
+
+type ModerationFlag struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	YapID      uuid.UUID
+	Reason     string
+	ResolvedAt sql.NullTime
+}
+
+type OauthClient struct {
+	ID           string
+	CreatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	Scopes       []string
+	RevokedAt    sql.NullTime
+}
+
+type OauthCode struct {
+	CodeHash      string
+	CreatedAt     time.Time
+	ClientID      string
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type RecoveryCode struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	ReplacedBy sql.NullString
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+	ClientID   sql.NullString
+	Scopes     []string
+}
+
+type SecurityEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Event     string
+	Details   string
 }
 
 type User struct {
@@ -27,12 +145,35 @@ type User struct {
 	Email           string
 	HashedPassword  string
 	HasYappyPremium bool
+	EmailVerifiedAt sql.NullTime
+}
+
+type UserIdentity struct {
+	Issuer      string
+	Subject     string
+	CreatedAt   time.Time
+	UserID      uuid.UUID
+	Email       string
+	LastLoginAt time.Time
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	CreatedAt    time.Time
+	ConfirmedAt  sql.NullTime
+	LastUsedStep sql.NullInt64
 }
 
 type Yap struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	Search         interface{}
+	ParentID       uuid.NullUUID
+	ConversationID uuid.UUID
+	RepostOfID     uuid.NullUUID
+	QuoteOfID      uuid.NullUUID
 }
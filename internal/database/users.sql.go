@@ -7,8 +7,11 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -20,7 +23,7 @@ VALUES (
     $1,
     $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password, has_yappy_premium
+RETURNING id, created_at, updated_at, email, hashed_password, has_yappy_premium, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -38,6 +41,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.HasYappyPremium,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -61,11 +65,22 @@ func (q *Queries) DeleteYap(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllYaps = `-- name: GetAllYaps :many
-SELECT id, created_at, updated_at, body, user_id FROM yaps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, search, parent_id, conversation_id, repost_of_id, quote_of_id FROM yaps
+WHERE
+    $1::timestamp IS NULL
+    OR (created_at, id) > ($1::timestamp, $2::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $3
 `
 
-func (q *Queries) GetAllYaps(ctx context.Context) ([]Yap, error) {
-	rows, err := q.db.QueryContext(ctx, getAllYaps)
+type GetAllYapsParams struct {
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) GetAllYaps(ctx context.Context, arg GetAllYapsParams) ([]Yap, error) {
+	rows, err := q.db.QueryContext(ctx, getAllYaps, arg.CursorCreatedAt, arg.CursorID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +94,11 @@ func (q *Queries) GetAllYaps(ctx context.Context) ([]Yap, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Search,
+			&i.ParentID,
+			&i.ConversationID,
+			&i.RepostOfID,
+			&i.QuoteOfID,
 		); err != nil {
 			return nil, err
 		}
@@ -94,7 +114,7 @@ func (q *Queries) GetAllYaps(ctx context.Context) ([]Yap, error) {
 }
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = $1
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at, client_id, scopes FROM refresh_tokens WHERE token = $1
 `
 
 func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -107,12 +127,19 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, has_yappy_premium FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, has_yappy_premium, email_verified_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -125,12 +152,32 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.HasYappyPremium,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, has_yappy_premium, email_verified_at FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.HasYappyPremium,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getYapByID = `-- name: GetYapByID :one
-SELECT id, created_at, updated_at, body, user_id FROM yaps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, search, parent_id, conversation_id, repost_of_id, quote_of_id FROM yaps WHERE id = $1
 `
 
 func (q *Queries) GetYapByID(ctx context.Context, id uuid.UUID) (Yap, error) {
@@ -142,16 +189,36 @@ func (q *Queries) GetYapByID(ctx context.Context, id uuid.UUID) (Yap, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Search,
+		&i.ParentID,
+		&i.ConversationID,
+		&i.RepostOfID,
+		&i.QuoteOfID,
 	)
 	return i, err
 }
 
 const getYapsByAuthor = `-- name: GetYapsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM yaps WHERE user_id = $1 ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, search, parent_id, conversation_id, repost_of_id, quote_of_id FROM yaps
+WHERE
+    user_id = $1
+    AND (
+        $2::timestamp IS NULL
+        OR (created_at, id) > ($2::timestamp, $3::uuid)
+    )
+ORDER BY created_at ASC, id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetYapsByAuthor(ctx context.Context, userID uuid.UUID) ([]Yap, error) {
-	rows, err := q.db.QueryContext(ctx, getYapsByAuthor, userID)
+type GetYapsByAuthorParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) GetYapsByAuthor(ctx context.Context, arg GetYapsByAuthorParams) ([]Yap, error) {
+	rows, err := q.db.QueryContext(ctx, getYapsByAuthor, arg.UserID, arg.CursorCreatedAt, arg.CursorID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -165,6 +232,11 @@ func (q *Queries) GetYapsByAuthor(ctx context.Context, userID uuid.UUID) ([]Yap,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Search,
+			&i.ParentID,
+			&i.ConversationID,
+			&i.RepostOfID,
+			&i.QuoteOfID,
 		); err != nil {
 			return nil, err
 		}
@@ -192,26 +264,131 @@ func (q *Queries) GivePremium(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const invalidatePasswordResetTokens = `-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET
+    used_at = NOW()
+WHERE
+    user_id = $1
+    AND used_at IS NULL
+`
+
+func (q *Queries) InvalidatePasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, invalidatePasswordResetTokens, userID)
+	return err
+}
+
+const listSessions = `-- name: ListSessions :many
+SELECT
+    t.family_id,
+    t.user_agent,
+    t.ip_address,
+    t.last_used_at,
+    t.expires_at,
+    t.client_id,
+    (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id)::timestamp AS signed_in_at
+FROM refresh_tokens t
+WHERE
+    t.user_id = $1
+    AND t.revoked_at IS NULL
+    AND t.expires_at > NOW()
+ORDER BY t.last_used_at DESC
+`
+
+type ListSessionsRow struct {
+	FamilyID   uuid.UUID
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	ClientID   sql.NullString
+	SignedInAt time.Time
+}
+
+func (q *Queries) ListSessions(ctx context.Context, userID uuid.UUID) ([]ListSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSessionsRow
+	for rows.Next() {
+		var i ListSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.ClientID,
+			&i.SignedInAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const newPasswordResetToken = `-- name: NewPasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, created_at, user_id, expires_at, used_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    NOW() + INTERVAL '1 hour',
+    NULL
+)
+`
+
+type NewPasswordResetTokenParams struct {
+	TokenHash string
+	UserID    uuid.UUID
+}
+
+func (q *Queries) NewPasswordResetToken(ctx context.Context, arg NewPasswordResetTokenParams) error {
+	_, err := q.db.ExecContext(ctx, newPasswordResetToken, arg.TokenHash, arg.UserID)
+	return err
+}
+
 const newRefreshToken = `-- name: NewRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at, client_id, scopes)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
-    NOW() + INTERTVAL '60 days',
-    NULL
+    NOW() + INTERVAL '60 days',
+    NULL,
+    $3,
+    NULL,
+    $4,
+    $5,
+    NOW(),
+    $6,
+    $7
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at, client_id, scopes
 `
 
 type NewRefreshTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+	Token     string
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	UserAgent string
+	IpAddress string
+	ClientID  sql.NullString
+	Scopes    []string
 }
 
 func (q *Queries) NewRefreshToken(ctx context.Context, arg NewRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, newRefreshToken, arg.Token, arg.UserID)
+	row := q.db.QueryRowContext(ctx, newRefreshToken, arg.Token, arg.UserID, arg.FamilyID, arg.UserAgent, arg.IpAddress, arg.ClientID, pq.Array(arg.Scopes))
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -220,29 +397,67 @@ func (q *Queries) NewRefreshToken(ctx context.Context, arg NewRefreshTokenParams
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
-const newYap = `-- name: NewYap :one
-INSERT INTO yaps (id, created_at, updated_at, body, user_id)
+const newSecurityEvent = `-- name: NewSecurityEvent :exec
+INSERT INTO security_events (id, created_at, user_id, event, details)
 VALUES (
-    gen_random_uuid (),
-    NOW(),
+    gen_random_uuid(),
     NOW(),
     $1,
-    $2 
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, body, user_id
+`
+
+type NewSecurityEventParams struct {
+	UserID  uuid.UUID
+	Event   string
+	Details string
+}
+
+func (q *Queries) NewSecurityEvent(ctx context.Context, arg NewSecurityEventParams) error {
+	_, err := q.db.ExecContext(ctx, newSecurityEvent, arg.UserID, arg.Event, arg.Details)
+	return err
+}
+
+const newYap = `-- name: NewYap :one
+INSERT INTO yaps (id, created_at, updated_at, body, user_id, parent_id, conversation_id, quote_of_id)
+SELECT
+    new_yap.id,
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    -- replies join their parent's conversation, everything else starts one
+    COALESCE(
+        (SELECT parent.conversation_id FROM yaps AS parent WHERE parent.id = $3),
+        new_yap.id
+    ),
+    $4
+FROM (SELECT gen_random_uuid () AS id) AS new_yap
+RETURNING id, created_at, updated_at, body, user_id, search, parent_id, conversation_id, repost_of_id, quote_of_id
 `
 
 type NewYapParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	QuoteOfID uuid.NullUUID
 }
 
 func (q *Queries) NewYap(ctx context.Context, arg NewYapParams) (Yap, error) {
-	row := q.db.QueryRowContext(ctx, newYap, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, newYap, arg.Body, arg.UserID, arg.ParentID, arg.QuoteOfID)
 	var i Yap
 	err := row.Scan(
 		&i.ID,
@@ -250,10 +465,30 @@ func (q *Queries) NewYap(ctx context.Context, arg NewYapParams) (Yap, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Search,
+		&i.ParentID,
+		&i.ConversationID,
+		&i.RepostOfID,
+		&i.QuoteOfID,
 	)
 	return i, err
 }
 
+const revokeAllRefreshTokensForUser = `-- name: RevokeAllRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW()
+WHERE
+    user_id = $1
+    AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllRefreshTokensForUser, userID)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET
@@ -268,10 +503,75 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	return err
 }
 
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW()
+WHERE
+    family_id = $1
+    AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW()
+WHERE
+    family_id = $1
+    AND user_id = $2
+    AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW(),
+    replaced_by = $2
+WHERE
+    token = $1
+    AND revoked_at IS NULL
+`
+
+type RotateRefreshTokenParams struct {
+	Token      string
+	ReplacedBy sql.NullString
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateRefreshToken, arg.Token, arg.ReplacedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :exec
 UPDATE users
 SET
     updated_at = NOW(),
+    -- a new address has to be verified again
+    email_verified_at = CASE WHEN email = $1 THEN email_verified_at ELSE NULL END,
     email = $1,
     hashed_password= $2
 WHERE
@@ -288,3 +588,46 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 	_, err := q.db.ExecContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
 	return err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+    updated_at = NOW(),
+    hashed_password = $2
+WHERE
+    id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const usePasswordResetToken = `-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET
+    used_at = NOW()
+WHERE
+    token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING token_hash, created_at, user_id, expires_at, used_at
+`
+
+func (q *Queries) UsePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, usePasswordResetToken, tokenHash)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+	)
+	return i, err
+}
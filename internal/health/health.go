@@ -0,0 +1,152 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/logging"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusDraining = "draining"
+)
+
+// Check returns nil if a dependency is usable. It should give up when ctx
+// is done.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check. Error is logged rather than served,
+// since probes are public and errors can name internal hosts.
+type Result struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"-"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report is what the readiness endpoint answers with.
+type Report struct {
+	Status    string            `json:"status"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    map[string]Result `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs a set of dependency checks and remembers the report for a
+// while, so frequent probes don't each hit the database.
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+	checks  []namedCheck
+
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	report Report
+	now    func() time.Time
+}
+
+// New returns a Checker that caches reports for ttl and gives each check
+// up to timeout.
+func New(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout, now: time.Now}
+}
+
+// Add registers a check. Call it before serving.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name, check})
+}
+
+// Drain marks the server as shutting down. From then on it reports not
+// ready without running any checks.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Check returns the cached report, running the checks again if it is older
+// than the ttl. Checks run concurrently and callers arriving while they run
+// wait for the same report.
+func (c *Checker) Check(ctx context.Context) Report {
+	if c.Draining() {
+		return Report{Status: StatusDraining, CheckedAt: c.now()}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.report.CheckedAt.IsZero() && c.now().Sub(c.report.CheckedAt) < c.ttl {
+		return c.report
+	}
+
+	//a probe giving up shouldn't fail the checks other probes are waiting on
+	ctx = context.WithoutCancel(ctx)
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, nc.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, CheckedAt: c.now(), Checks: make(map[string]Result, len(c.checks))}
+	for i, nc := range c.checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFailing
+			logging.FromContext(ctx).WarnContext(ctx, "Readiness check failed", "check", nc.name, "error", results[i].Error)
+		}
+	}
+	c.report = report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	result := Result{Status: StatusOK, DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// ServeHTTP answers readiness probes: 200 when every check passes, 503
+// when one fails or the server is draining.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// Live answers liveness probes. It only shows the process is serving, so
+// it passes even while draining or when a dependency is down.
+func Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Report{Status: StatusOK, CheckedAt: time.Now()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
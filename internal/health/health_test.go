@@ -0,0 +1,128 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/logging"
+)
+
+func TestCheckCaches(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := New(5*time.Second, time.Second)
+	c.now = func() time.Time { return now }
+	var runs atomic.Int32
+	c.Add("db", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	c.Check(context.Background())
+	now = now.Add(4 * time.Second)
+	report := c.Check(context.Background())
+	if runs.Load() != 1 {
+		t.Errorf("expected the cached report within the ttl, checks ran %d times", runs.Load())
+	}
+	if report.Status != StatusOK || report.Checks["db"].Status != StatusOK {
+		t.Errorf("unexpected report %+v", report)
+	}
+	now = now.Add(time.Second)
+	c.Check(context.Background())
+	if runs.Load() != 2 {
+		t.Errorf("expected checks to run again after the ttl, ran %d times", runs.Load())
+	}
+}
+
+func TestCheckFailures(t *testing.T) {
+	c := New(time.Minute, 10*time.Millisecond)
+	c.Add("ok", func(ctx context.Context) error { return nil })
+	c.Add("broken", func(ctx context.Context) error { return errors.New("connection refused") })
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	//a probe that already gave up still gets real results
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := c.Check(ctx)
+	if report.Status != StatusFailing {
+		t.Errorf("expected failing, got %q", report.Status)
+	}
+	expected := map[string]Result{
+		"ok":     {Status: StatusOK},
+		"broken": {Status: StatusFailing, Error: "connection refused"},
+		"slow":   {Status: StatusFailing, Error: context.DeadlineExceeded.Error()},
+	}
+	for name, want := range expected {
+		got := report.Checks[name]
+		if got.Status != want.Status || got.Error != want.Error {
+			t.Errorf("%s: expected %+v, got %+v", name, want, got)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	healthy := true
+	c := New(0, time.Second)
+	c.Add("db", func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("down")
+		}
+		return nil
+	})
+	get := func() (int, Report) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		var report Report
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		return w.Code, report
+	}
+
+	if code, report := get(); code != http.StatusOK || report.Status != StatusOK {
+		t.Errorf("expected 200 ok, got %d %q", code, report.Status)
+	}
+	//the error is logged but not served
+	healthy = false
+	var logs bytes.Buffer
+	oldLogger := slog.Default()
+	slog.SetDefault(logging.New(&logs, slog.LevelInfo))
+	defer slog.SetDefault(oldLogger)
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"db":{"status":"failing"`) {
+		t.Errorf("expected 503 with db failing, got %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "down") {
+		t.Errorf("expected the error to stay out of the response, got %s", w.Body.String())
+	}
+	if !strings.Contains(logs.String(), `"check":"db","error":"down"`) {
+		t.Errorf("expected the error in the log, got %s", logs.String())
+	}
+	healthy = true
+	c.Drain()
+	if code, report := get(); code != http.StatusServiceUnavailable || report.Status != StatusDraining || report.Checks != nil {
+		t.Errorf("expected 503 draining without checks, got %d %+v", code, report)
+	}
+
+	w = httptest.NewRecorder()
+	Live(w, httptest.NewRequest("GET", "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected liveness to pass while draining, got %d", w.Code)
+	}
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestQueryName(t *testing.T) {
+	tests := map[string]string{
+		"-- name: GetUserByEmail :one\nSELECT * FROM users WHERE email = $1": "GetUserByEmail",
+		"-- name: DeleteAllUsers :exec\nDELETE FROM users":                   "DeleteAllUsers",
+		"SELECT 1": "unnamed",
+	}
+	for query, expected := range tests {
+		if got := QueryName(query); got != expected {
+			t.Errorf("QueryName(%q) = %q, expected %q", query, got, expected)
+		}
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	for _, id := range []string{"abc", "0123456789abcdef", "req-1.2_3", strings.Repeat("a", 128), NewRequestID()} {
+		if !ValidRequestID(id) {
+			t.Errorf("expected %q to be valid", id)
+		}
+	}
+	for _, id := range []string{"", strings.Repeat("a", 129), "has space", "new\nline", `"quoted"`, "ünicode"} {
+		if ValidRequestID(id) {
+			t.Errorf("expected %q to be invalid", id)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, expected := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn} {
+		level, err := ParseLevel(s)
+		if err != nil || level != expected {
+			t.Errorf("ParseLevel(%q) = %v, %v, expected %v", s, level, err, expected)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("expected the default logger for a bare context")
+	}
+	logger := New(&bytes.Buffer{}, slog.LevelInfo)
+	if FromContext(NewContext(context.Background(), logger)) != logger {
+		t.Error("expected the logger stored in the context")
+	}
+}
+
+// fakeDB fails ExecContext with err and panics on everything else.
+type fakeDB struct {
+	DBTX
+	err error
+}
+
+func (db fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, db.err
+}
+
+func TestDB(t *testing.T) {
+	var b bytes.Buffer
+	ctx := NewContext(context.Background(), New(&b, slog.LevelDebug).With("request_id", "abc"))
+	query := "-- name: DeleteAllUsers :exec\nDELETE FROM users"
+
+	WrapDB(fakeDB{}).ExecContext(ctx, query)
+	out := b.String()
+	for _, part := range []string{`"level":"DEBUG"`, `"msg":"database query"`, `"request_id":"abc"`, `"query":"DeleteAllUsers"`, `"duration_ms":`} {
+		if !strings.Contains(out, part) {
+			t.Errorf("expected %s in %s", part, out)
+		}
+	}
+
+	b.Reset()
+	WrapDB(fakeDB{err: errors.New("connection refused")}).ExecContext(ctx, query)
+	out = b.String()
+	for _, part := range []string{`"level":"ERROR"`, `"msg":"database query failed"`, `"error":"connection refused"`} {
+		if !strings.Contains(out, part) {
+			t.Errorf("expected %s in %s", part, out)
+		}
+	}
+
+	b.Reset()
+	WrapDB(fakeDB{err: sql.ErrNoRows}).ExecContext(ctx, query)
+	if strings.Contains(b.String(), "ERROR") {
+		t.Errorf("expected no rows not to be logged as an error: %s", b.String())
+	}
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	m := &Memory{}
+	msg := Message{To: "a@example.com", Subject: "hi", Body: "hello"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := m.Messages()
+	if len(got) != 1 || got[0] != msg {
+		t.Errorf("expected [%v], got %v", msg, got)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	m := NewFile(path, "yappy@example.com")
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		err := m.Send(context.Background(), Message{To: to, Subject: "Verify", Body: "line one\nline two"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read mail file: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{"To: a@example.com\r\n", "To: b@example.com\r\n", "From: yappy@example.com\r\n", "line one\r\nline two"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected mail file to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatStripsHeaderInjection(t *testing.T) {
+	out := string(format("yappy@example.com", Message{
+		To:      "a@example.com\r\nBcc: victim@example.com",
+		Subject: "hi",
+		Body:    "hello",
+	}))
+	if strings.Contains(out, "\r\nBcc:") {
+		t.Errorf("expected injected header to be stripped, got:\n%s", out)
+	}
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("http_requests_total", "Requests served.", "method", "code")
+	inFlight := r.NewGauge("http_requests_in_flight", "Requests being served.")
+	latency := r.NewHistogram("http_request_duration_seconds", "Request latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("db_open_connections", "Open connections.", func() float64 { return 3 })
+
+	requests.Inc("POST", "201")
+	requests.Inc("GET", "200")
+	requests.Add(2, "GET", "200")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	latency.Observe(0.05, "GET /api/yaps")
+	latency.Observe(0.1, "GET /api/yaps")
+	latency.Observe(3, "GET /api/yaps")
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 3
+http_requests_total{method="POST",code="201"} 1
+# HELP http_requests_in_flight Requests being served.
+# TYPE http_requests_in_flight gauge
+http_requests_in_flight 1
+# HELP http_request_duration_seconds Request latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{route="GET /api/yaps",le="0.1"} 2
+http_request_duration_seconds_bucket{route="GET /api/yaps",le="1"} 2
+http_request_duration_seconds_bucket{route="GET /api/yaps",le="+Inf"} 3
+http_request_duration_seconds_sum{route="GET /api/yaps"} 3.15
+http_request_duration_seconds_count{route="GET /api/yaps"} 3
+# HELP db_open_connections Open connections.
+# TYPE db_open_connections gauge
+db_open_connections 3
+`
+	if b.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+	if n != int64(len(expected)) {
+		t.Errorf("expected %d bytes written, got %d", len(expected), n)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("odd_total", "Help with \\ and\nnewline.", "value")
+	c.Inc("quote \" backslash \\ newline \n")
+	c.Inc("")
+	var b strings.Builder
+	r.WriteTo(&b)
+	for _, line := range []string{
+		`# HELP odd_total Help with \\ and\nnewline.`,
+		`odd_total{value=""} 1`,
+		`odd_total{value="quote \" backslash \\ newline \n"} 1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	r := NewRegistry()
+	c := r.NewCounter("things_total", "Things.", "kind")
+	expectPanic("duplicate name", func() { r.NewGauge("things_total", "Again.") })
+	expectPanic("wrong label count", func() { c.Inc() })
+	expectPanic("negative counter", func() { c.Add(-1, "a") })
+	expectPanic("unsorted buckets", func() { r.NewHistogram("h", "H.", []float64{1, 0.5}) })
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("hits_total", "Hits.").Inc()
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "hits_total 1\n") {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
@@ -0,0 +1,202 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Action is what happens to a yap when a rule matches it. Actions are ordered
+// by severity so the strictest matching rule wins.
+type Action int
+
+const (
+	Allow Action = iota
+	Flag
+	Mask
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Allow:
+		return "allow"
+	case Flag:
+		return "flag"
+	case Mask:
+		return "mask"
+	case Reject:
+		return "reject"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+func parseAction(s string) (Action, error) {
+	switch strings.ToLower(s) {
+	case "flag":
+		return Flag, nil
+	case "mask":
+		return Mask, nil
+	case "reject":
+		return Reject, nil
+	}
+	return Allow, fmt.Errorf("unknown moderation action %q", s)
+}
+
+// MaskText is what a masked word is replaced with.
+const MaskText = "****"
+
+// Rule pairs a single word with the action taken when it appears.
+type Rule struct {
+	Word   string
+	Action Action
+}
+
+// Result is the outcome of moderating a piece of text. Text has every Mask
+// rule applied; Matches lists the rules that fired, in order of appearance.
+// Flagged is set when any Flag rule fired, even if a stricter action won, so
+// masked text still gets reviewed.
+type Result struct {
+	Action  Action
+	Text    string
+	Matches []Rule
+	Flagged bool
+}
+
+// Moderator decides what to do with user submitted text before it is saved.
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (Result, error)
+}
+
+// DefaultRules is the list used when no word list file is configured.
+var DefaultRules = []Rule{
+	{Word: "lol", Action: Mask},
+	{Word: "fortnite", Action: Mask},
+	{Word: "damn", Action: Mask},
+}
+
+// WordList matches whole words case-insensitively after NFKC normalization,
+// so "Damn!", "DAMN" and "ｄａｍｎ" all hit a rule for "damn" while
+// "damnation" does not.
+type WordList struct {
+	rules map[string]Rule
+}
+
+func NewWordList(rules []Rule) *WordList {
+	wl := &WordList{rules: make(map[string]Rule, len(rules))}
+	for _, rule := range rules {
+		key := normalize(rule.Word)
+		//keep the strictest action if a word is listed twice
+		if existing, ok := wl.rules[key]; ok && existing.Action >= rule.Action {
+			continue
+		}
+		wl.rules[key] = rule
+	}
+	return wl
+}
+
+func (wl *WordList) Moderate(ctx context.Context, text string) (Result, error) {
+	res := Result{Action: Allow}
+	var b strings.Builder
+	last := 0
+	for _, w := range words(text) {
+		rule, ok := wl.rules[normalize(text[w.start:w.end])]
+		if !ok {
+			continue
+		}
+		res.Matches = append(res.Matches, rule)
+		res.Action = max(res.Action, rule.Action)
+		if rule.Action == Flag {
+			res.Flagged = true
+		}
+		if rule.Action == Mask {
+			b.WriteString(text[last:w.start])
+			b.WriteString(MaskText)
+			last = w.end
+		}
+	}
+	b.WriteString(text[last:])
+	res.Text = b.String()
+	return res, nil
+}
+
+// LoadRules reads a word list file. Each non-empty line is a word optionally
+// followed by an action (mask, reject or flag; mask if omitted). Lines
+// starting with # are comments.
+func LoadRules(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRules(f)
+}
+
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected \"word [action]\", got %q", line, text)
+		}
+		rule := Rule{Word: fields[0], Action: Mask}
+		if ws := words(rule.Word); len(ws) != 1 || ws[0] != (span{0, len(rule.Word)}) {
+			return nil, fmt.Errorf("line %d: %q is not a single word", line, rule.Word)
+		}
+		if len(fields) == 2 {
+			action, err := parseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rule.Action = action
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// span is the byte range of a word in the original text.
+type span struct {
+	start, end int
+}
+
+// words splits text into runs of letters, digits and combining marks.
+// Everything else is a word boundary.
+func words(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+func normalize(word string) string {
+	return strings.ToLower(norm.NFKC.String(word))
+}
@@ -0,0 +1,161 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWordListModerate(t *testing.T) {
+	wl := NewWordList([]Rule{
+		{Word: "damn", Action: Mask},
+		{Word: "lol", Action: Mask},
+		{Word: "spam", Action: Flag},
+		{Word: "slur", Action: Reject},
+	})
+
+	tests := []struct {
+		name           string
+		text           string
+		expectedAction Action
+		expectedText   string
+		expectedHits   int
+		expectedFlag   bool
+	}{
+		{
+			name:           "Clean",
+			text:           "hello world",
+			expectedAction: Allow,
+			expectedText:   "hello world",
+			expectedHits:   0,
+		},
+		{
+			name:           "Case Insensitive",
+			text:           "LOL that was fun, lol",
+			expectedAction: Mask,
+			expectedText:   "**** that was fun, ****",
+			expectedHits:   2,
+		},
+		{
+			name:           "Punctuation Boundary",
+			text:           "Damn! that hurt",
+			expectedAction: Mask,
+			expectedText:   "****! that hurt",
+			expectedHits:   1,
+		},
+		{
+			name:           "Substring Not Matched",
+			text:           "damnation and lollipops",
+			expectedAction: Allow,
+			expectedText:   "damnation and lollipops",
+			expectedHits:   0,
+		},
+		{
+			name:           "Fullwidth Normalized",
+			text:           "ｄａｍｎ it",
+			expectedAction: Mask,
+			expectedText:   "**** it",
+			expectedHits:   1,
+		},
+		{
+			name:           "Flag Keeps Text",
+			text:           "buy my spam",
+			expectedAction: Flag,
+			expectedText:   "buy my spam",
+			expectedHits:   1,
+			expectedFlag:   true,
+		},
+		{
+			name:           "Flag Survives Mask",
+			text:           "lol buy my spam",
+			expectedAction: Mask,
+			expectedText:   "**** buy my spam",
+			expectedHits:   2,
+			expectedFlag:   true,
+		},
+		{
+			name:           "Strictest Action Wins",
+			text:           "damn slur spam",
+			expectedAction: Reject,
+			expectedText:   "**** slur spam",
+			expectedHits:   3,
+			expectedFlag:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := wl.Moderate(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Action != tt.expectedAction {
+				t.Errorf("expected action %v, got %v", tt.expectedAction, res.Action)
+			}
+			if res.Text != tt.expectedText {
+				t.Errorf("expected text %q, got %q", tt.expectedText, res.Text)
+			}
+			if len(res.Matches) != tt.expectedHits {
+				t.Errorf("expected %d matches, got %d", tt.expectedHits, len(res.Matches))
+			}
+			if res.Flagged != tt.expectedFlag {
+				t.Errorf("expected flagged %v, got %v", tt.expectedFlag, res.Flagged)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectError   bool
+		expectedRules []Rule
+	}{
+		{
+			name:  "Valid List",
+			input: "# comment\n\nlol\ndamn mask\nspam flag\nslur REJECT\n",
+			expectedRules: []Rule{
+				{Word: "lol", Action: Mask},
+				{Word: "damn", Action: Mask},
+				{Word: "spam", Action: Flag},
+				{Word: "slur", Action: Reject},
+			},
+		},
+		{
+			name:        "Unknown Action",
+			input:       "lol delete\n",
+			expectError: true,
+		},
+		{
+			name:        "Too Many Fields",
+			input:       "lol mask now\n",
+			expectError: true,
+		},
+		{
+			name:        "Not A Single Word",
+			input:       "l.o.l\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(strings.NewReader(tt.input))
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if len(rules) != len(tt.expectedRules) {
+				t.Fatalf("expected %d rules, got %d", len(tt.expectedRules), len(rules))
+			}
+			for i := range rules {
+				if rules[i] != tt.expectedRules[i] {
+					t.Errorf("rule %d: expected %+v, got %+v", i, tt.expectedRules[i], rules[i])
+				}
+			}
+		})
+	}
+}
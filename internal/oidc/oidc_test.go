@@ -0,0 +1,386 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeProvider is a minimal OpenID provider. Tests register codes on it
+// directly instead of going through a login page.
+type fakeProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	secret string
+
+	mu    sync.Mutex
+	kid   string
+	key   *ecdsa.PrivateKey
+	codes map[string]fakeCode
+}
+
+type fakeCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	f := &fakeProvider{t: t, secret: "client-secret", codes: map[string]fakeCode{}}
+	f.rotate("key-1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                f.server.URL,
+			AuthorizationEndpoint: f.server.URL + "/authorize",
+			TokenEndpoint:         f.server.URL + "/token",
+			JWKSURI:               f.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		pub := f.key.PublicKey
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "EC",
+			"use": "sig",
+			"kid": f.kid,
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	mux.HandleFunc("POST /token", f.token)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeProvider) rotate(kid string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		f.t.Fatalf("failed to generate key: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kid, f.key = kid, key
+}
+
+func (f *fakeProvider) sign(claims jwt.MapClaims) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = f.kid
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		f.t.Fatalf("failed to sign id token: %v", err)
+	}
+	return signed
+}
+
+func (f *fakeProvider) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            f.server.URL,
+		"sub":            "user-123",
+		"aud":            "yappy",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          "staff@example.com",
+		"email_verified": true,
+		"name":           "Staff Member",
+	}
+}
+
+func (f *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != "yappy" || secret != f.secret {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+		return
+	}
+	f.mu.Lock()
+	code, ok := f.codes[r.PostFormValue("code")]
+	delete(f.codes, r.PostFormValue("code"))
+	f.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"id_token":     f.sign(code.claims),
+	})
+}
+
+func (f *fakeProvider) provider(t *testing.T) *Provider {
+	p, err := Discover(context.Background(), Config{
+		Issuer:       f.server.URL,
+		ClientID:     "yappy",
+		ClientSecret: f.secret,
+		RedirectURL:  "http://localhost:8080/api/login/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	return p
+}
+
+func TestExchange(t *testing.T) {
+	f := newFakeProvider(t)
+	p := f.provider(t)
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	authURL, err := url.Parse(p.AuthCodeURL("state-1", "nonce-1", challenge))
+	if err != nil {
+		t.Fatalf("invalid auth url: %v", err)
+	}
+	q := authURL.Query()
+	if q.Get("client_id") != "yappy" || q.Get("state") != "state-1" || q.Get("nonce") != "nonce-1" ||
+		q.Get("code_challenge") != challenge || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("unexpected auth url %s", authURL)
+	}
+
+	f.codes["code-1"] = fakeCode{challenge: challenge, claims: f.claims("nonce-1")}
+	token, err := p.Exchange(context.Background(), "code-1", verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if token.Subject != "user-123" || token.Email != "staff@example.com" || !token.EmailVerified || token.Issuer != f.server.URL {
+		t.Errorf("unexpected id token %+v", token)
+	}
+	//codes are single use
+	if _, err := p.Exchange(context.Background(), "code-1", verifier, "nonce-1"); err == nil {
+		t.Error("expected reused code to fail")
+	}
+
+	f.codes["code-2"] = fakeCode{challenge: challenge, claims: f.claims("nonce-2")}
+	if _, err := p.Exchange(context.Background(), "code-2", "wrong-verifier-wrong-verifier-wrong-verifier", "nonce-2"); err == nil {
+		t.Error("expected wrong code verifier to fail")
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	f := newFakeProvider(t)
+	_, err := Discover(context.Background(), Config{Issuer: f.server.URL + "/", ClientID: "yappy"})
+	if err == nil {
+		t.Error("expected issuer mismatch to fail discovery")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	f := newFakeProvider(t)
+	p := f.provider(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		token       func() string
+		expectError bool
+		verified    bool
+	}{
+		{
+			name:     "Valid",
+			token:    func() string { return f.sign(f.claims("nonce")) },
+			verified: true,
+		},
+		{
+			name: "Email Verified As String",
+			token: func() string {
+				c := f.claims("nonce")
+				c["email_verified"] = "true"
+				return f.sign(c)
+			},
+			verified: true,
+		},
+		{
+			name: "Email Not Verified",
+			token: func() string {
+				c := f.claims("nonce")
+				c["email_verified"] = false
+				return f.sign(c)
+			},
+		},
+		{
+			name: "Wrong Nonce",
+			token: func() string {
+				return f.sign(f.claims("other"))
+			},
+			expectError: true,
+		},
+		{
+			name: "Wrong Audience",
+			token: func() string {
+				c := f.claims("nonce")
+				c["aud"] = "someone-else"
+				return f.sign(c)
+			},
+			expectError: true,
+		},
+		{
+			name: "Multiple Audiences Without azp",
+			token: func() string {
+				c := f.claims("nonce")
+				c["aud"] = []string{"yappy", "someone-else"}
+				return f.sign(c)
+			},
+			expectError: true,
+		},
+		{
+			name: "Multiple Audiences With azp",
+			token: func() string {
+				c := f.claims("nonce")
+				c["aud"] = []string{"yappy", "someone-else"}
+				c["azp"] = "yappy"
+				return f.sign(c)
+			},
+			verified: true,
+		},
+		{
+			name: "Wrong Issuer",
+			token: func() string {
+				c := f.claims("nonce")
+				c["iss"] = "https://evil.example.com"
+				return f.sign(c)
+			},
+			expectError: true,
+		},
+		{
+			name: "Expired",
+			token: func() string {
+				c := f.claims("nonce")
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+				return f.sign(c)
+			},
+			expectError: true,
+		},
+		{
+			name: "Unknown Key",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, f.claims("nonce"))
+				token.Header["kid"] = "key-1"
+				signed, _ := token.SignedString(rsaKey)
+				return signed
+			},
+			expectError: true,
+		},
+		{
+			name: "Symmetric Algorithm",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, f.claims("nonce"))
+				token.Header["kid"] = "key-1"
+				signed, _ := token.SignedString([]byte(f.secret))
+				return signed
+			},
+			expectError: true,
+		},
+		{
+			name: "None Algorithm",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, f.claims("nonce"))
+				signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return signed
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := p.Verify(context.Background(), tt.token(), "nonce")
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.expectError && token.EmailVerified != tt.verified {
+				t.Errorf("expected email_verified %v, got %v", tt.verified, token.EmailVerified)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	f := newFakeProvider(t)
+	p := f.provider(t)
+	if _, err := p.Verify(context.Background(), f.sign(f.claims("nonce")), "nonce"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.rotate("key-2")
+	rotated := f.sign(f.claims("nonce"))
+	//keys were just fetched, so the new kid has to wait out the refresh interval
+	if _, err := p.Verify(context.Background(), rotated, "nonce"); err == nil {
+		t.Error("expected new key to be refused until the refresh interval passes")
+	}
+	p.mu.Lock()
+	p.fetchedAt = time.Now().Add(-keyRefreshInterval)
+	p.mu.Unlock()
+	if _, err := p.Verify(context.Background(), rotated, "nonce"); err != nil {
+		t.Errorf("expected rotated key to be fetched: %v", err)
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         jwk
+		expectError bool
+	}{
+		{
+			name: "Small RSA Key",
+			key: jwk{Kty: "RSA", N: base64.RawURLEncoding.EncodeToString(big.NewInt(1<<40 + 15).Bytes()),
+				E: "AQAB"},
+			expectError: true,
+		},
+		{name: "Unknown Curve", key: jwk{Kty: "EC", Crv: "P-192"}, expectError: true},
+		{name: "Point Off Curve", key: jwk{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}, expectError: true},
+		{name: "Ed25519", key: jwk{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(make([]byte, 32))}},
+		{name: "Symmetric", key: jwk{Kty: "oct"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.key.publicKey()
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestExchangeErrors(t *testing.T) {
+	f := newFakeProvider(t)
+	f.secret = "rotated-secret"
+	p := f.provider(t)
+	p.cfg.ClientSecret = "client-secret"
+	_, err := p.Exchange(context.Background(), "code", "verifier", "nonce")
+	if err == nil || errors.Is(err, ErrNonceMismatch) {
+		t.Errorf("expected invalid_client error, got %v", err)
+	}
+}
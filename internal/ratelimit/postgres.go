@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+)
+
+// Postgres is a Store shared by every server using the same database. Each
+// Take locks the bucket's row for the length of a short transaction, and
+// the database's clock is used so servers don't need synced clocks.
+type Postgres struct {
+	conn *sql.DB
+	db   *database.Queries
+}
+
+func NewPostgres(conn *sql.DB) *Postgres {
+	return &Postgres{conn: conn, db: database.New(conn)}
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	tx, err := p.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+	qtx := p.db.WithTx(tx)
+	row, err := qtx.LockRateLimitBucket(ctx, database.LockRateLimitBucketParams{
+		Key:    key,
+		Tokens: float64(limit.Requests),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	b := bucket{tokens: row.Tokens, updated: row.UpdatedAt}
+	res := b.take(limit, row.Now)
+	err = qtx.UpdateRateLimitBucket(ctx, database.UpdateRateLimitBucketParams{
+		Key:       key,
+		Tokens:    b.tokens,
+		UpdatedAt: b.updated,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return res, tx.Commit()
+}
+
+// Prune deletes buckets untouched for idle. As long as idle is longer than
+// every limit's Per they would have refilled anyway.
+func (p *Postgres) Prune(ctx context.Context, idle time.Duration) error {
+	return p.db.DeleteIdleRateLimitBuckets(ctx, time.Now().Add(-idle))
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Limit
+		expectError bool
+	}{
+		{input: "30/1m", expected: Limit{Requests: 30, Per: time.Minute}},
+		{input: " 5/10s ", expected: Limit{Requests: 5, Per: 10 * time.Second}},
+		{input: "off", expected: Limit{}},
+		{input: "30", expectError: true},
+		{input: "0/1m", expectError: true},
+		{input: "-1/1m", expectError: true},
+		{input: "30/0s", expectError: true},
+		{input: "30/minute", expectError: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.input)
+		if tt.expectError && err == nil {
+			t.Errorf("%q: expected error, got nil", tt.input)
+		}
+		if !tt.expectError && err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.input, err)
+		}
+		if got != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMemoryTake(t *testing.T) {
+	m := NewMemory()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	ctx := context.Background()
+
+	//a fresh bucket allows a full burst
+	for i := 2; i >= 0; i-- {
+		res, _ := m.Take(ctx, "a", limit)
+		if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+			t.Fatalf("expected request allowed with %d remaining, got %+v", i, res)
+		}
+	}
+	res, _ := m.Take(ctx, "a", limit)
+	if res.Allowed {
+		t.Fatal("expected request over the burst to be refused")
+	}
+	if res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Errorf("expected retry after 1s and reset in 3s, got %+v", res)
+	}
+
+	//other keys have their own bucket
+	if res, _ := m.Take(ctx, "b", limit); !res.Allowed {
+		t.Error("expected another key to be allowed")
+	}
+
+	//one token comes back per second
+	now = now.Add(time.Second)
+	if res, _ := m.Take(ctx, "a", limit); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected refilled token to be allowed, got %+v", res)
+	}
+	if res, _ := m.Take(ctx, "a", limit); res.Allowed {
+		t.Error("expected request to be refused again")
+	}
+
+	//refilling never goes past the burst size
+	now = now.Add(time.Hour)
+	if res, _ := m.Take(ctx, "a", limit); res.Remaining != 2 {
+		t.Errorf("expected bucket capped at 3, got %+v", res)
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 10, Per: time.Hour}
+	ctx := context.Background()
+	m.Take(ctx, "idle", Limit{Requests: 1, Per: time.Second})
+	m.Take(ctx, "busy", limit)
+
+	now = now.Add(sweepInterval)
+	m.Take(ctx, "other", limit)
+	if _, ok := m.buckets["idle"]; ok {
+		t.Error("expected refilled bucket to be swept")
+	}
+	if _, ok := m.buckets["busy"]; !ok {
+		t.Error("expected bucket still refilling to be kept")
+	}
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxTerms caps how many terms a single query can expand to so a user can't
+// hand Postgres an arbitrarily large tsquery.
+const maxTerms = 32
+
+// ParseQuery turns user search input into a to_tsquery expression.
+//
+// Bare words are ANDed together, "double quoted" words must appear next to
+// each other in order, and a trailing * makes a word match as a prefix:
+//
+//	go "hello world" yap*  ->  go & (hello <-> world) & yap:*
+//
+// Everything except letters and digits is stripped, so the result is always
+// valid tsquery syntax.
+func ParseQuery(q string) (string, error) {
+	var groups []string
+	terms := 0
+	for i, part := range strings.Split(q, `"`) {
+		//odd parts sit between a pair of quotes
+		phrase := i%2 == 1
+		var words []string
+		for _, field := range strings.Fields(part) {
+			words = append(words, lexemes(field)...)
+		}
+		if len(words) == 0 {
+			continue
+		}
+		terms += len(words)
+		if terms > maxTerms {
+			return "", fmt.Errorf("search query is too long (max %d terms)", maxTerms)
+		}
+		if phrase && len(words) > 1 {
+			groups = append(groups, "("+strings.Join(words, " <-> ")+")")
+			continue
+		}
+		groups = append(groups, words...)
+	}
+	if len(groups) == 0 {
+		return "", fmt.Errorf("search query is empty")
+	}
+	return strings.Join(groups, " & "), nil
+}
+
+// lexemes splits a single whitespace-delimited field on anything that isn't a
+// letter or digit. A trailing * on the field marks its last lexeme as a prefix.
+func lexemes(field string) []string {
+	prefix := strings.HasSuffix(field, "*")
+	words := strings.FieldsFunc(field, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if prefix && len(words) > 0 {
+		words[len(words)-1] += ":*"
+	}
+	return words
+}
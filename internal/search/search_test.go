@@ -0,0 +1,97 @@
+package search
+
+import "testing"
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedQuery string
+		expectError   bool
+	}{
+		{
+			name:          "Single Word",
+			query:         "golang",
+			expectedQuery: "golang",
+			expectError:   false,
+		},
+		{
+			name:          "Multiple Words",
+			query:         "golang  postgres",
+			expectedQuery: "golang & postgres",
+			expectError:   false,
+		},
+		{
+			name:          "Phrase",
+			query:         `"hello world"`,
+			expectedQuery: "(hello <-> world)",
+			expectError:   false,
+		},
+		{
+			name:          "Prefix",
+			query:         "yap*",
+			expectedQuery: "yap:*",
+			expectError:   false,
+		},
+		{
+			name:          "Mixed",
+			query:         `go "hello world" yap*`,
+			expectedQuery: "go & (hello <-> world) & yap:*",
+			expectError:   false,
+		},
+		{
+			name:          "Prefix Inside Phrase",
+			query:         `"hello wor*"`,
+			expectedQuery: "(hello <-> wor:*)",
+			expectError:   false,
+		},
+		{
+			name:          "Unterminated Quote",
+			query:         `"hello world`,
+			expectedQuery: "(hello <-> world)",
+			expectError:   false,
+		},
+		{
+			name:          "Operators Stripped",
+			query:         "a&b | !c:*",
+			expectedQuery: "a & b & c:*",
+			expectError:   false,
+		},
+		{
+			name:          "Unicode",
+			query:         "café naïve",
+			expectedQuery: "café & naïve",
+			expectError:   false,
+		},
+		{
+			name:        "Empty",
+			query:       "   ",
+			expectError: true,
+		},
+		{
+			name:        "Only Punctuation",
+			query:       `"!!" ***`,
+			expectError: true,
+		},
+		{
+			name:        "Too Many Terms",
+			query:       "a b c d e f g h i j k l m n o p q r s t u v w x y z a b c d e f g",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseQuery(tt.query)
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if query != tt.expectedQuery {
+				t.Errorf("expected query %q, got %q", tt.expectedQuery, query)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by Open when no blob exists for a key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps opaque blobs addressed by the SHA-256 of their contents, so
+// uploading the same file twice stores it once.
+type BlobStore interface {
+	// Put stores everything read from r and returns its key and size.
+	Put(ctx context.Context, r io.Reader) (key string, size int64, err error)
+	// Open returns the blob for key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+}
+
+// Filesystem is a BlobStore backed by a local directory. Blobs are sharded
+// into subdirectories by the first two bytes of their key.
+type Filesystem struct {
+	dir string
+}
+
+func NewFilesystem(dir string) (*Filesystem, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Filesystem{dir: dir}, nil
+}
+
+func (fs *Filesystem) Put(ctx context.Context, r io.Reader) (string, int64, error) {
+	//write to a temp file while hashing, then move it into place
+	tmp, err := os.CreateTemp(fs.dir, ".upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(h.Sum(nil))
+	path := fs.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return key, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return key, size, nil
+}
+
+func (fs *Filesystem) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	if !ValidKey(key) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(fs.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (fs *Filesystem) path(key string) string {
+	return filepath.Join(fs.dir, key[0:2], key[2:4], key)
+}
+
+// ValidKey reports whether key looks like something Put could have returned.
+// Keys end up in file paths, so anything else is rejected outright.
+func ValidKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range key {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
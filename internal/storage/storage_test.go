@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilesystemPutOpen(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	key, size, err := fs.Put(ctx, strings.NewReader("hello yappy"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ValidKey(key) {
+		t.Errorf("expected a valid key, got %q", key)
+	}
+	if size != int64(len("hello yappy")) {
+		t.Errorf("expected size %d, got %d", len("hello yappy"), size)
+	}
+
+	again, _, err := fs.Put(ctx, strings.NewReader("hello yappy"))
+	if err != nil {
+		t.Fatalf("unexpected error on second put: %v", err)
+	}
+	if again != key {
+		t.Errorf("expected identical content to get the same key, got %q and %q", key, again)
+	}
+
+	f, err := fs.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("failed to read blob: %v", err)
+	}
+	if string(data) != "hello yappy" {
+		t.Errorf("expected %q, got %q", "hello yappy", data)
+	}
+}
+
+func TestFilesystemLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFilesystem(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if _, _, err := fs.Put(context.Background(), strings.NewReader("data")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".upload-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("expected temp files to be cleaned up, found %v", leftovers)
+	}
+}
+
+func TestFilesystemOpenMissing(t *testing.T) {
+	fs, err := NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{
+			name: "Unknown Key",
+			key:  strings.Repeat("ab", 32),
+		},
+		{
+			name: "Path Traversal",
+			key:  "../../etc/passwd",
+		},
+		{
+			name: "Uppercase Hex",
+			key:  strings.Repeat("AB", 32),
+		},
+		{
+			name: "Empty",
+			key:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fs.Open(context.Background(), tt.key)
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewFilesystemCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "media")
+	if _, err := NewFilesystem(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("expected %s to be created", dir)
+	}
+}
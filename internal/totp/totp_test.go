@@ -0,0 +1,111 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcKey is the shared secret used by the RFC 4226 and RFC 6238 test vectors.
+const rfcKey = "12345678901234567890"
+
+func TestHOTP(t *testing.T) {
+	//RFC 4226 appendix D
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, want := range expected {
+		if got := hotp([]byte(rfcKey), uint64(counter), 6); got != want {
+			t.Errorf("counter %d: expected %s, got %s", counter, want, got)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	//RFC 6238 appendix B, SHA1
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got := hotp([]byte(rfcKey), uint64(Step(time.Unix(tt.unix, 0))), 8)
+		if got != tt.code {
+			t.Errorf("time %d: expected %s, got %s", tt.unix, tt.code, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatalf("failed to make code: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		at          time.Time
+		code        string
+		expectValid bool
+	}{
+		{
+			name:        "Current Step",
+			at:          now,
+			code:        code,
+			expectValid: true,
+		},
+		{
+			name:        "One Step Late",
+			at:          now.Add(Period * time.Second),
+			code:        code,
+			expectValid: true,
+		},
+		{
+			name:        "Two Steps Late",
+			at:          now.Add(2 * Period * time.Second),
+			code:        code,
+			expectValid: false,
+		},
+		{
+			name:        "Wrong Length",
+			at:          now,
+			code:        code[:5],
+			expectValid: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(secret, tt.code, tt.at)
+			if ok != tt.expectValid {
+				t.Fatalf("expected valid %v, got %v", tt.expectValid, ok)
+			}
+			if ok && step != Step(now) {
+				t.Errorf("expected step %d, got %d", Step(now), step)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Yappy", "user@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Yappy:user@example.com?") {
+		t.Errorf("unexpected uri %q", uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("uri did not parse: %v", err)
+	}
+	if u.Query().Get("secret") != "JBSWY3DPEHPK3PXP" || u.Query().Get("issuer") != "Yappy" {
+		t.Errorf("unexpected query %v", u.Query())
+	}
+}
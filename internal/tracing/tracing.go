@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name is the instrumentation scope of every span made here.
+const Name = "github.com/F0RG-2142/chirpy-proj"
+
+type Config struct {
+	// Exporters is a comma separated list of otlp, stdout and file. Empty
+	// or none records nothing, but trace context is still passed along.
+	Exporters string
+	// File is where the file exporter appends spans, one JSON object each.
+	File        string
+	ServiceName string
+}
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. The returned func flushes and stops the exporters.
+//
+// The otlp exporter is configured with the usual OTEL_EXPORTER_OTLP_*
+// variables, and OTEL_TRACES_SAMPLER picks the sampler.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporters []sdktrace.SpanExporter
+	var files []*os.File
+	fail := func(err error) (func(context.Context) error, error) {
+		for _, exporter := range exporters {
+			exporter.Shutdown(ctx)
+		}
+		for _, f := range files {
+			f.Close()
+		}
+		return nil, err
+	}
+	for _, name := range strings.Split(cfg.Exporters, ",") {
+		var exporter sdktrace.SpanExporter
+		var err error
+		switch name = strings.TrimSpace(name); name {
+		case "", "none":
+			continue
+		case "otlp":
+			exporter, err = otlptracehttp.New(ctx)
+		case "stdout":
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		case "file":
+			if cfg.File == "" {
+				return fail(errors.New("file exporter needs a file"))
+			}
+			f, ferr := os.OpenFile(cfg.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+			if ferr != nil {
+				return fail(ferr)
+			}
+			files = append(files, f)
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		default:
+			return fail(fmt.Errorf("unknown exporter %q", name))
+		}
+		if err != nil {
+			return fail(fmt.Errorf("%s exporter: %w", name, err))
+		}
+		exporters = append(exporters, exporter)
+	}
+	if len(exporters) == 0 {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	//OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return fail(err)
+	}
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	for _, exporter := range exporters {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		for _, f := range files {
+			err = errors.Join(err, f.Close())
+		}
+		return err
+	}, nil
+}
+
+// Start starts a span with the global tracer provider.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(Name).Start(ctx, name, opts...)
+}
+
+// End ends span, marking it failed if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
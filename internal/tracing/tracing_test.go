@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans points the global tracer provider at a recorder for the
+// rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(old) })
+	return recorder
+}
+
+// fakeDB fails ExecContext with err and panics on everything else.
+type fakeDB struct {
+	database.DBTX
+	err error
+}
+
+func (db fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil, errors.New("query ran outside its span")
+	}
+	return nil, db.err
+}
+
+func TestDB(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, parent := Start(context.Background(), "parent")
+	query := "-- name: DeleteAllUsers :exec\nDELETE FROM users"
+
+	if _, err := WrapDB(fakeDB{}).ExecContext(ctx, query); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	WrapDB(fakeDB{err: sql.ErrNoRows}).ExecContext(ctx, query)
+	WrapDB(fakeDB{err: errors.New("connection refused")}).ExecContext(ctx, query)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+	for i, span := range spans[:3] {
+		if span.Name() != "DeleteAllUsers" || span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("span %d: unexpected name %q or kind %v", i, span.Name(), span.SpanKind())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d: expected to be a child of the request span", i)
+		}
+		attrs := attribute.NewSet(span.Attributes()...)
+		if v, _ := attrs.Value("db.system"); v.AsString() != "postgresql" {
+			t.Errorf("span %d: unexpected db.system %q", i, v.AsString())
+		}
+		if v, _ := attrs.Value("db.query.text"); v.AsString() != query {
+			t.Errorf("span %d: unexpected db.query.text %q", i, v.AsString())
+		}
+	}
+	for i, expected := range []codes.Code{codes.Unset, codes.Unset, codes.Error} {
+		if got := spans[i].Status().Code; got != expected {
+			t.Errorf("span %d: expected status %v, got %v", i, expected, got)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	old := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(old)
+
+	if _, err := Setup(context.Background(), Config{Exporters: "carrier-pigeon"}); err == nil {
+		t.Error("expected an error for an unknown exporter")
+	}
+	if _, err := Setup(context.Background(), Config{Exporters: "file"}); err == nil {
+		t.Error("expected an error for the file exporter without a file")
+	}
+	shutdown, err := Setup(context.Background(), Config{Exporters: "none"})
+	if err != nil || shutdown(context.Background()) != nil {
+		t.Errorf("expected none to set up and shut down cleanly, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err = Setup(context.Background(), Config{Exporters: " file ", File: path, ServiceName: "yappy-test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, span := Start(context.Background(), "something slow")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error shutting down: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read spans: %v", err)
+	}
+	for _, part := range []string{`"Name":"something slow"`, `"Value":"yappy-test"`} {
+		if !strings.Contains(string(b), part) {
+			t.Errorf("expected %s in %s", part, b)
+		}
+	}
+}
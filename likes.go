@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+func likeYap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	user_id := claims.UserID
+	yap_id, err := uuid.Parse(r.PathValue("yapId"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	_, err = Cfg.db.GetYapByID(r.Context(), yap_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Yap not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to like yap"}`, http.StatusInternalServerError)
+		return
+	}
+	//liking twice is a no-op
+	params := database.LikeYapParams{
+		UserID: user_id,
+		YapID:  yap_id,
+	}
+	if err := Cfg.db.LikeYap(r.Context(), params); err != nil {
+		logErrorf(r.Context(), "Error liking yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to like yap"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func unlikeYap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	user_id := claims.UserID
+	yap_id, err := uuid.Parse(r.PathValue("yapId"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	params := database.UnlikeYapParams{
+		UserID: user_id,
+		YapID:  yap_id,
+	}
+	if err := Cfg.db.UnlikeYap(r.Context(), params); err != nil {
+		logErrorf(r.Context(), "Error unliking yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to unlike yap"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getUserLikes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	user_id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	//most recently liked first, paged on when the like happened
+	rows, err := Cfg.db.GetLikedYaps(r.Context(), database.GetLikedYapsParams{
+		UserID:          user_id,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching likes for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to fetch likes"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := yapPage{Yaps: []yapResponse{}}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		resp.NextCursor = cursor{CreatedAt: last.LikedAt, ID: last.ID}.encode()
+	}
+	for _, row := range rows {
+		resp.Yaps = append(resp.Yaps, yapResponse{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			Body:           row.Body,
+			UserID:         row.UserID,
+			ParentID:       row.ParentID,
+			ConversationID: row.ConversationID,
+			RepostOfID:     row.RepostOfID,
+			QuoteOfID:      row.QuoteOfID,
+		})
+	}
+	if err := hydrateYaps(r.Context(), optionalUserID(r), resp.Yaps); err != nil {
+		logErrorf(r.Context(), "Error hydrating yaps: %v", err)
+		http.Error(w, `{"error":"Failed to fetch yaps"}`, http.StatusInternalServerError)
+		return
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// withLikeStats fills in the like count and liked_by_me flag for each yap
+// with a single query. viewer may be uuid.Nil for anonymous requests.
+func withLikeStats(ctx context.Context, viewer uuid.UUID, yaps []yapResponse) error {
+	if len(yaps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(yaps))
+	for _, yap := range yaps {
+		ids = append(ids, yap.ID)
+	}
+	stats, err := Cfg.db.GetLikeStats(ctx, database.GetLikeStatsParams{
+		ViewerID: viewer,
+		YapIds:   ids,
+	})
+	if err != nil {
+		return err
+	}
+	byYap := make(map[uuid.UUID]database.GetLikeStatsRow, len(stats))
+	for _, s := range stats {
+		byYap[s.YapID] = s
+	}
+	for i := range yaps {
+		s := byYap[yaps[i].ID]
+		yaps[i].Likes = s.LikeCount
+		yaps[i].LikedByMe = s.LikedByMe
+	}
+	return nil
+}
+
+// optionalUserID returns the caller's user ID when a valid access token or
+// api key is sent, and uuid.Nil otherwise. Used by endpoints that work without auth but
+// personalize their response when it is there.
+func optionalUserID(r *http.Request) uuid.UUID {
+	claims, err := authenticate(r)
+	if err != nil || !claims.HasScope(auth.ScopeRead) {
+		return uuid.Nil
+	}
+	return claims.UserID
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+// Kinds of key failed logins are counted against.
+const (
+	loginKeyAccount = "account"
+	loginKeyIP      = "ip"
+)
+
+// loginPolicy is how failed logins against one key are throttled. The first
+// FreeAttempts failures cost nothing, then each one doubles the wait before
+// the next try, up to MaxDelay. LockoutAfter failures lock the key out for
+// Lockout. Counts reset after ResetAfter without a failure.
+type loginPolicy struct {
+	FreeAttempts int32
+	LockoutAfter int32
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Lockout      time.Duration
+	ResetAfter   time.Duration
+}
+
+// loginPolicies are per email address and per client IP. The IP policy is
+// looser since offices and phone networks share addresses.
+var loginPolicies = map[string]loginPolicy{
+	loginKeyAccount: {
+		FreeAttempts: 5,
+		LockoutAfter: 10,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Lockout:      15 * time.Minute,
+		ResetAfter:   time.Hour,
+	},
+	loginKeyIP: {
+		FreeAttempts: 20,
+		LockoutAfter: 100,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Lockout:      15 * time.Minute,
+		ResetAfter:   time.Hour,
+	},
+}
+
+// delay is how long to block logins after the given number of failures.
+func (p loginPolicy) delay(failures int32) time.Duration {
+	switch {
+	case failures >= p.LockoutAfter:
+		return p.Lockout
+	case failures < p.FreeAttempts:
+		return 0
+	}
+	shift := failures - p.FreeAttempts
+	if shift > 30 {
+		return p.MaxDelay
+	}
+	return min(p.BaseDelay<<shift, p.MaxDelay)
+}
+
+// loginKeys are the keys a login attempt is counted against. Emails are
+// compared case insensitively so changing case doesn't buy more guesses.
+func loginKeys(r *http.Request, email string) map[string]string {
+	return map[string]string{
+		loginKeyAccount: strings.ToLower(strings.TrimSpace(email)),
+		loginKeyIP:      clientIP(r),
+	}
+}
+
+// loginBlockedFor returns how long until a login for these keys may be
+// attempted again, or zero if it may be attempted now.
+func loginBlockedFor(ctx context.Context, keys map[string]string) (time.Duration, error) {
+	var wait time.Duration
+	for kind, key := range keys {
+		failure, err := Cfg.db.GetLoginFailure(ctx, database.GetLoginFailureParams{Kind: kind, Key: key})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		wait = max(wait, time.Until(failure.BlockedUntil))
+	}
+	return wait, nil
+}
+
+// recordLoginFailure counts a failed login against every key and blocks
+// the ones that are over their policy. user_id is uuid.Nil for unknown
+// emails, which are tracked exactly like real ones.
+func recordLoginFailure(ctx context.Context, keys map[string]string, user_id uuid.UUID) {
+	now := time.Now()
+	for kind, key := range keys {
+		policy := loginPolicies[kind]
+		failure, err := Cfg.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Kind:        kind,
+			Key:         key,
+			FailedAt:    now,
+			ResetBefore: now.Add(-policy.ResetAfter),
+		})
+		if err != nil {
+			logErrorf(ctx, "Error recording failed login for %s %q: %v", kind, key, err)
+			continue
+		}
+		delay := policy.delay(failure.Failures)
+		if delay == 0 {
+			continue
+		}
+		err = Cfg.db.BlockLogin(ctx, database.BlockLoginParams{Kind: kind, Key: key, BlockedUntil: now.Add(delay)})
+		if err != nil {
+			logErrorf(ctx, "Error blocking logins for %s %q: %v", kind, key, err)
+		}
+		if failure.Failures == policy.LockoutAfter {
+			logWarnf(ctx, "Locked out logins for %s %q after %d failures", kind, key, failure.Failures)
+			if kind == loginKeyAccount && user_id != uuid.Nil {
+				logSecurityEvent(ctx, user_id, "account_locked",
+					fmt.Sprintf("%d failed logins, locked until %s", failure.Failures, now.Add(delay).Format(time.RFC3339)))
+			}
+		}
+	}
+}
+
+// clearAccountFailures forgets failed logins against an email after a
+// successful one. Failures from the IP are kept, otherwise an attacker could
+// reset their count by logging into an account of their own.
+func clearAccountFailures(ctx context.Context, keys map[string]string) {
+	_, err := Cfg.db.ClearLoginFailures(ctx, database.ClearLoginFailuresParams{
+		Kind: loginKeyAccount,
+		Key:  keys[loginKeyAccount],
+	})
+	if err != nil {
+		logErrorf(ctx, "Error clearing failed logins for %q: %v", keys[loginKeyAccount], err)
+	}
+}
+
+// writeLoginBlocked answers a throttled login with 429 and a Retry-After.
+func writeLoginBlocked(w http.ResponseWriter, wait time.Duration) {
+	failedLogins.Inc("throttled")
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+	http.Error(w, `{"error":"Too many failed login attempts, try again later"}`, http.StatusTooManyRequests)
+}
+
+// dummyPasswordHash is checked against when the email is unknown, so a
+// login for a missing account takes as long as a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("not the password of any account")
+	if err != nil {
+		log.Fatal("Failed to hash dummy password:", err)
+	}
+	return hash
+})
+
+// pruneLoginFailures deletes long forgotten failure counts every interval
+// until ctx is done, so spraying random emails can't grow the table forever.
+func pruneLoginFailures(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cutoff := time.Now().Add(-loginPolicies[loginKeyAccount].ResetAfter)
+			if err := Cfg.db.DeleteStaleLoginFailures(ctx, cutoff); err != nil {
+				logErrorf(ctx, "Error pruning failed logins: %v", err)
+			}
+		}
+	}
+}
+
+// requireAdmin checks the request carries ADMIN_KEY as an ApiKey. Admin
+// endpoints are off entirely when ADMIN_KEY is unset.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	adminKey := os.Getenv("ADMIN_KEY")
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if adminKey == "" || err != nil || subtle.ConstantTimeCompare([]byte(apiKey), []byte(adminKey)) != 1 {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+type loginBlockResponse struct {
+	Kind          string    `json:"kind"`
+	Key           string    `json:"key"`
+	Failures      int32     `json:"failures"`
+	Locked        bool      `json:"locked"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+	BlockedUntil  time.Time `json:"blocked_until"`
+}
+
+// listLoginBlocks shows admins which emails and IPs can't log in right now.
+func listLoginBlocks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireAdmin(w, r) {
+		return
+	}
+	blocks, err := Cfg.db.ListLoginBlocks(r.Context(), time.Now())
+	if err != nil {
+		logErrorf(r.Context(), "Error listing login blocks: %v", err)
+		http.Error(w, `{"error":"Failed to list lockouts"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := make([]loginBlockResponse, 0, len(blocks))
+	for _, block := range blocks {
+		resp = append(resp, loginBlockResponse{
+			Kind:          block.Kind,
+			Key:           block.Key,
+			Failures:      block.Failures,
+			Locked:        block.Failures >= loginPolicies[block.Kind].LockoutAfter,
+			FirstFailedAt: block.FirstFailedAt,
+			LastFailedAt:  block.LastFailedAt,
+			BlockedUntil:  block.BlockedUntil,
+		})
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// clearLoginBlock lets an admin unlock an email or IP early.
+func clearLoginBlock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireAdmin(w, r) {
+		return
+	}
+	kind := r.PathValue("kind")
+	if _, ok := loginPolicies[kind]; !ok {
+		http.Error(w, `{"error":"Kind must be account or ip"}`, http.StatusBadRequest)
+		return
+	}
+	rows, err := Cfg.db.ClearLoginFailures(r.Context(), database.ClearLoginFailuresParams{
+		Kind: kind,
+		Key:  r.PathValue("key"),
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error clearing login block: %v", err)
+		http.Error(w, `{"error":"Failed to clear lockout"}`, http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, `{"error":"Lockout not found"}`, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/logging"
+	"github.com/F0RG-2142/chirpy-proj/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDHeader is taken from the request when a proxy already set it,
+// and always echoed on the response.
+const requestIDHeader = "X-Request-ID"
+
+type requestInfoKey struct{}
+
+// requestInfo is what the middleware works out about a request, plus what
+// handlers learn about it that belongs in its access log line.
+type requestInfo struct {
+	// route is the mux pattern the request matches, "" if none.
+	route string
+	// claims are the request's access token's, nil without a valid one.
+	claims *auth.Claims
+	userID uuid.UUID
+}
+
+// withRequestInfo returns the requestInfo an outer middleware stored on r,
+// or works it out and stores it on a copy of r. Doing it once means one
+// route lookup and one signature check per request.
+func (cfg *apiConfig) withRequestInfo(mux *http.ServeMux, r *http.Request) (*requestInfo, *http.Request) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return info, r
+	}
+	info := &requestInfo{}
+	_, info.route = mux.Handler(r)
+	//most requests carry an access token, api keys are filled in by authenticate
+	if token, err := auth.GetBearerToken(r.Header); err == nil {
+		if claims, err := cfg.keys.ValidateJWT(token); err == nil {
+			info.claims = &claims
+			info.userID = claims.UserID
+		}
+	}
+	return info, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+}
+
+// setRequestUser records who a request was authenticated as.
+func setRequestUser(ctx context.Context, user_id uuid.UUID) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = user_id
+	}
+}
+
+// logErrorf logs a printf style message at error level with the request's
+// logger, so it carries the request ID.
+func logErrorf(ctx context.Context, format string, args ...any) {
+	logging.FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(format, args...))
+}
+
+// logWarnf is logErrorf at warning level.
+func logWarnf(ctx context.Context, format string, args ...any) {
+	logging.FromContext(ctx).WarnContext(ctx, fmt.Sprintf(format, args...))
+}
+
+// wrapDB logs and traces the queries run through db.
+func wrapDB(db database.DBTX) database.DBTX {
+	return logging.WrapDB(tracing.WrapDB(db))
+}
+
+// txQueries is database.Queries.WithTx for queries that are logged and
+// traced like the ones run through Cfg.db.
+func txQueries(tx *sql.Tx) *database.Queries {
+	return database.New(wrapDB(tx))
+}
+
+// middlewareLogging gives every request an ID and a logger carrying it,
+// then writes one access log line when the request is done.
+func (cfg *apiConfig) middlewareLogging(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get(requestIDHeader)
+		if !logging.ValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		info, r := cfg.withRequestInfo(mux, r)
+		logger := slog.Default().With("request_id", requestID)
+		span := trace.SpanContextFromContext(r.Context())
+		if span.IsValid() {
+			logger = logger.With("trace_id", span.TraceID().String())
+		}
+		if info.claims != nil {
+			logger = logger.With("user_id", info.claims.UserID)
+		}
+		ctx := logging.NewContext(r.Context(), logger)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if rec.Status() >= 500 {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("request_id", requestID),
+			slog.String("method", r.Method),
+			slog.String("route", info.route),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.Status()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", rec.bytes),
+			slog.String("remote_ip", clientIP(r)),
+		}
+		if span.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", span.TraceID().String()))
+		}
+		if info.userID != uuid.Nil {
+			attrs = append(attrs, slog.String("user_id", info.userID.String()))
+		}
+		slog.Default().LogAttrs(ctx, level, "request", attrs...)
+	})
+}
@@ -1,67 +1,251 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
-	"html/template"
+	"errors"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
-	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/F0RG-2142/chirpy-proj/internal/auth"
 	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/health"
+	"github.com/F0RG-2142/chirpy-proj/internal/logging"
+	"github.com/F0RG-2142/chirpy-proj/internal/mail"
+	"github.com/F0RG-2142/chirpy-proj/internal/moderation"
+	"github.com/F0RG-2142/chirpy-proj/internal/oidc"
+	"github.com/F0RG-2142/chirpy-proj/internal/ratelimit"
+	"github.com/F0RG-2142/chirpy-proj/internal/storage"
+	"github.com/F0RG-2142/chirpy-proj/internal/tracing"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	platform       string
-	secret         string
+	db         *database.Queries
+	conn       *sql.DB
+	platform   string
+	secret     string
+	keys       *auth.Keyring
+	moderator  moderation.Moderator
+	blobs      storage.BlobStore
+	mailer     mail.Mailer
+	baseURL    string
+	oidc       *oidc.Provider
+	limiter    ratelimit.Store
+	rateLimits map[string]ratelimit.Limit
+	health     *health.Checker
 }
 
 var Cfg apiConfig
 
 func main() {
 	godotenv.Load()
+	//json logs, log.Printf included
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		log.Fatal("Invalid LOG_LEVEL:", err)
+	}
+	slog.SetDefault(logging.New(os.Stderr, level))
+	//cancelled on the first signal, a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+	serverCfg, err := loadServerConfig(os.Getenv)
+	if err != nil {
+		log.Fatal("Failed to load server config:", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporters:   os.Getenv("TRACE_EXPORTERS"),
+		File:        os.Getenv("TRACE_FILE"),
+		ServiceName: "yappy",
+	})
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
 	dbURL := os.Getenv("DB_URL")
 	Cfg.platform = os.Getenv("PLATFORM")
 	Cfg.secret = os.Getenv("JWT_SECRET")
+	keys, err := loadKeyring()
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+	Cfg.keys = keys
+	rateLimits, err := loadRateLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatal("Failed to load rate limits:", err)
+	}
+	Cfg.rateLimits = rateLimits
+	//word list for the moderation pipeline, built in defaults if unset
+	rules := moderation.DefaultRules
+	if path := os.Getenv("MODERATION_RULES"); path != "" {
+		loaded, err := moderation.LoadRules(path)
+		if err != nil {
+			log.Fatal("Failed to load moderation rules:", err)
+		}
+		rules = loaded
+	}
+	Cfg.moderator = moderation.NewWordList(rules)
+	//uploaded media lives on local disk for now
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./media"
+	}
+	blobs, err := storage.NewFilesystem(mediaDir)
+	if err != nil {
+		log.Fatal("Failed to open media directory:", err)
+	}
+	Cfg.blobs = blobs
+	//outgoing mail goes to SMTP when configured, otherwise to a local file
+	Cfg.baseURL = os.Getenv("BASE_URL")
+	if Cfg.baseURL == "" {
+		Cfg.baseURL = "http://localhost:8080"
+	}
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Yappy <no-reply@yappy.local>"
+	}
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		Cfg.mailer = mail.NewSMTP(smtpAddr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom)
+	} else {
+		mailFile := os.Getenv("MAIL_FILE")
+		if mailFile == "" {
+			mailFile = "./mail.log"
+		}
+		Cfg.mailer = mail.NewFile(mailFile, mailFrom)
+	}
+	//single sign-on is optional
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oidc.Discover(ctx, oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  Cfg.baseURL + "/api/login/oidc/callback",
+		})
+		cancel()
+		if err != nil {
+			log.Fatal("Failed to set up OIDC provider:", err)
+		}
+		Cfg.oidc = provider
+	}
 
 	db, _ := sql.Open("postgres", dbURL)
-	defer db.Close()
 	if err := db.Ping(); err != nil {
 		log.Fatal("Failed to ping database:", err)
 	}
-	Cfg.db = database.New(db)
+	Cfg.conn = db
+	Cfg.db = database.New(wrapDB(db))
+	Cfg.health = newHealthChecker(db, Cfg.blobs)
+	registerDBMetrics(db)
+	go pruneLoginFailures(ctx, time.Hour)
+	//buckets live in postgres when several servers share the limits
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", "memory":
+		Cfg.limiter = ratelimit.NewMemory()
+	case "postgres":
+		limiter := ratelimit.NewPostgres(db)
+		go pruneRateLimits(ctx, limiter, time.Hour)
+		Cfg.limiter = limiter
+	default:
+		log.Fatal("Unknown RATE_LIMIT_STORE:", store)
+	}
+	dummyPasswordHash()
 
 	mux := http.NewServeMux()
-	mux.Handle("/app/", http.StripPrefix("/app/", Cfg.middlewareMetricsInc(http.FileServer(http.Dir("./")))))
-	mux.Handle("/assets", Cfg.middlewareMetricsInc(http.FileServer(http.Dir("./"))))
-	mux.Handle("GET /api/healthz", Cfg.middlewareMetricsInc(http.HandlerFunc(readiness)))
-	mux.Handle("GET /admin/metrics", Cfg.middlewareMetricsInc(http.HandlerFunc(metrics)))
-	mux.Handle("POST /admin/reset", http.HandlerFunc(reset))
+	mux.Handle("/app/", http.StripPrefix("/app/", http.FileServer(http.Dir("./"))))
+	mux.Handle("/assets", http.FileServer(http.Dir("./")))
+	mux.Handle("GET /.well-known/jwks.json", http.HandlerFunc(jwks))
+	mux.Handle("GET /livez", http.HandlerFunc(health.Live))
+	mux.Handle("GET /readyz", Cfg.health)
+	mux.Handle("GET /api/healthz", Cfg.health)
+	mux.Handle("GET /metrics", registry.Handler())
+	mux.Handle("GET /admin/lockouts", http.HandlerFunc(listLoginBlocks))
+	mux.Handle("DELETE /admin/lockouts/{kind}/{key}", http.HandlerFunc(clearLoginBlock))
 	mux.Handle("POST /api/users", http.HandlerFunc(newUser))
 	mux.Handle("POST /api/reset", http.HandlerFunc(resetDb))
 	mux.Handle("POST /api/login", http.HandlerFunc(login))
 	mux.Handle("POST /api/yaps", http.HandlerFunc(yaps))
-	mux.Handle("GET /api/yaps/{authorId}", http.HandlerFunc(getYaps))
+	mux.Handle("GET /api/yaps", http.HandlerFunc(getYaps))
+	mux.Handle("GET /api/yaps/search", http.HandlerFunc(searchYaps))
+	mux.Handle("GET /api/yaps/{yapId}/thread", http.HandlerFunc(getThread))
 	mux.Handle("GET /api/yaps/{yapId}", http.HandlerFunc(getYap))
 	mux.Handle("POST /api/refresh", http.HandlerFunc(refresh))
 	mux.Handle("POST /api/revoke", http.HandlerFunc(revoke))
+	mux.Handle("GET /api/sessions", http.HandlerFunc(listSessions))
+	mux.Handle("DELETE /api/sessions", http.HandlerFunc(revokeAllSessions))
+	mux.Handle("DELETE /api/sessions/{id}", http.HandlerFunc(revokeSession))
+	mux.Handle("POST /api/keys", http.HandlerFunc(createAPIKey))
+	mux.Handle("GET /api/keys", http.HandlerFunc(listAPIKeys))
+	mux.Handle("DELETE /api/keys/{id}", http.HandlerFunc(revokeAPIKey))
+	mux.Handle("POST /api/oauth/clients", http.HandlerFunc(registerOAuthClient))
+	mux.Handle("GET /api/oauth/clients", http.HandlerFunc(listOAuthClients))
+	mux.Handle("DELETE /api/oauth/clients/{id}", http.HandlerFunc(deleteOAuthClient))
+	mux.Handle("GET /api/oauth/authorize", http.HandlerFunc(authorizeInfo))
+	mux.Handle("POST /api/oauth/authorize", http.HandlerFunc(authorize))
+	mux.Handle("POST /api/oauth/token", http.HandlerFunc(oauthToken))
+	mux.Handle("POST /api/oauth/introspect", http.HandlerFunc(introspectToken))
+	mux.Handle("POST /api/oauth/revoke", http.HandlerFunc(revokeOAuthToken))
 	mux.Handle("PUT /api/users", http.HandlerFunc(update))
 	mux.Handle("DELETE /api/chirps/{yapID}", http.HandlerFunc(deleteYap))
 	mux.Handle("POST /api/payment_platform/webhooks", http.HandlerFunc(payment))
+	mux.Handle("POST /api/users/{id}/follow", http.HandlerFunc(follow))
+	mux.Handle("DELETE /api/users/{id}/follow", http.HandlerFunc(unfollow))
+	mux.Handle("GET /api/timeline", http.HandlerFunc(timeline))
+	mux.Handle("POST /api/yaps/{yapId}/like", http.HandlerFunc(likeYap))
+	mux.Handle("DELETE /api/yaps/{yapId}/like", http.HandlerFunc(unlikeYap))
+	mux.Handle("GET /api/users/{id}/likes", http.HandlerFunc(getUserLikes))
+	mux.Handle("POST /api/yaps/{yapId}/repost", http.HandlerFunc(repostYap))
+	mux.Handle("DELETE /api/yaps/{yapId}/repost", http.HandlerFunc(unrepostYap))
+	mux.Handle("POST /api/yaps/{yapId}/attachments", http.HandlerFunc(uploadAttachment))
+	mux.Handle("GET /media/{key}", http.HandlerFunc(serveMedia))
+	mux.Handle("GET /api/users/verify", http.HandlerFunc(verifyEmail))
+	mux.Handle("POST /api/users/verify/resend", http.HandlerFunc(resendVerification))
+	mux.Handle("POST /api/password/forgot", http.HandlerFunc(forgotPassword))
+	mux.Handle("POST /api/password/reset", http.HandlerFunc(resetPassword))
+	mux.Handle("GET /api/login/oidc", http.HandlerFunc(oidcLogin))
+	mux.Handle("GET /api/login/oidc/callback", http.HandlerFunc(oidcCallback))
+	mux.Handle("POST /api/login/mfa", http.HandlerFunc(loginMFA))
+	mux.Handle("POST /api/users/mfa/totp", http.HandlerFunc(enrollTOTP))
+	mux.Handle("POST /api/users/mfa/totp/confirm", http.HandlerFunc(confirmTOTP))
+	mux.Handle("DELETE /api/users/mfa/totp", http.HandlerFunc(disableTOTP))
 
-	server := &http.Server{Handler: mux, Addr: ":8080"}
-	fmt.Println("Listening on http://localhost:8080/")
-	server.ListenAndServe()
+	handler := Cfg.middlewareRateLimit(mux)
+	handler = Cfg.middlewareMetrics(mux, handler)
+	handler = Cfg.middlewareLogging(mux, handler)
+	handler = Cfg.middlewareTracing(mux, handler)
+	server := serverCfg.newServer(":8080", handler)
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Fatal("Failed to listen:", err)
+	}
+	slog.Info("Listening on http://localhost:8080/")
+	serveErr := serve(ctx, server, ln, Cfg.health, serverCfg)
+	if serveErr != nil {
+		slog.Error("Server stopped", "error", serveErr.Error())
+	}
+	//nothing is using them any more
+	if err := db.Close(); err != nil {
+		slog.Error("Error closing database", "error", err.Error())
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err.Error())
+	}
+	if serveErr != nil {
+		os.Exit(1)
+	}
+	slog.Info("Stopped")
 }
 
 func payment(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +271,7 @@ func payment(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding request: %v", err)
+		logErrorf(r.Context(), "Error decoding request: %v", err)
 		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
@@ -104,16 +288,16 @@ func payment(w http.ResponseWriter, r *http.Request) {
 
 func deleteYap(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	token, err := auth.GetBearerToken(r.Header)
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
 	if err != nil {
-		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusForbidden)
 		return
 	}
-	user_id, err := auth.ValidateJWT(token, Cfg.secret)
-	if err != nil {
-		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusForbidden)
+	if !requireScope(w, claims, auth.ScopeWrite) {
 		return
 	}
+	user_id := claims.UserID
 	id, err := uuid.Parse(r.URL.Query().Get("yapId"))
 	if err != nil {
 		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
@@ -144,11 +328,15 @@ func update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
-	user_id, err := auth.ValidateJWT(token, Cfg.secret)
+	claims, err := Cfg.keys.ValidateJWT(token)
 	if err != nil {
 		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusForbidden)
 		return
 	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
 	//decode request
 	req := struct {
 		Email    string `json:"email"`
@@ -158,12 +346,18 @@ func update(w http.ResponseWriter, r *http.Request) {
 		Password: "",
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding request: %v", err)
+		logErrorf(r.Context(), "Error decoding request: %v", err)
 		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
+	//remember the old address to tell whether it changed
+	old_user, err := Cfg.db.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusFailedDependency)
+		return
+	}
 	//hash passw and update user
-	hashed_pass, err := auth.HashPassword(req.Password)
+	hashed_pass, err := hashPassword(r.Context(), req.Password)
 	if err != nil {
 
 	}
@@ -183,18 +377,25 @@ func update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusFailedDependency)
 		return
 	}
+	if user.Email != old_user.Email {
+		if err := sendVerificationEmail(r.Context(), user); err != nil {
+			logErrorf(r.Context(), "Error sending verification email to user %q: %v", user.ID, err)
+		}
+	}
 	//create response struct, marshal, and respond
 	resp := struct {
 		ID                uuid.UUID `json:"id"`
 		CreatedAt         time.Time `json:"created_at"`
 		UpdatedAt         time.Time `json:"updated_at"`
 		Email             string    `json:"email"`
+		EmailVerified     bool      `json:"email_verified"`
 		Has_yappy_premium bool      `json:"has_yappy_premium"`
 	}{
 		ID:                user.ID,
 		CreatedAt:         user.CreatedAt,
 		UpdatedAt:         user.UpdatedAt,
 		Email:             user.Email,
+		EmailVerified:     user.EmailVerifiedAt.Valid,
 		Has_yappy_premium: user.HasYappyPremium,
 	}
 	jsonResp, err := json.Marshal(resp)
@@ -215,7 +416,7 @@ func revoke(w http.ResponseWriter, r *http.Request) {
 	}
 	refreshToken, err := Cfg.db.GetRefreshToken(r.Context(), token)
 	if err != nil {
-		log.Printf("Error fetching refresh token: %v", err)
+		logErrorf(r.Context(), "Error fetching refresh token: %v", err)
 		http.Error(w, `{"error":"Invalid refresh token"}`, http.StatusForbidden)
 		return
 	}
@@ -235,11 +436,17 @@ func refresh(w http.ResponseWriter, r *http.Request) {
 	}
 	refreshToken, err := Cfg.db.GetRefreshToken(r.Context(), token)
 	if err != nil {
-		log.Printf("Error fetching refresh token: %v", err)
+		logErrorf(r.Context(), "Error fetching refresh token: %v", err)
 		http.Error(w, `{"error":"Invalid refresh token"}`, http.StatusForbidden)
 		return
 	}
-	if !refreshToken.RevokedAt.Valid {
+	//a token that was already rotated should never come back, assume it leaked
+	if refreshToken.ReplacedBy.Valid {
+		revokeRefreshTokenFamily(r.Context(), refreshToken)
+		http.Error(w, `{"error":"Refresh token is revoked"}`, http.StatusForbidden)
+		return
+	}
+	if refreshToken.RevokedAt.Valid {
 		http.Error(w, `{"error":"Refresh token is revoked"}`, http.StatusForbidden)
 		return
 	}
@@ -247,26 +454,39 @@ func refresh(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"Refresh token is expired"}`, http.StatusForbidden)
 		return
 	}
-	tokenSecret := Cfg.secret
-	if tokenSecret == "" {
-		log.Println("JWT_SECRET not set")
-		http.Error(w, `{"error":"Server configuration error"}`, http.StatusInternalServerError)
+	//apps have to authenticate at the oauth token endpoint instead
+	if refreshToken.ClientID.Valid {
+		http.Error(w, `{"error":"Refresh token belongs to an app, use /api/oauth/token"}`, http.StatusForbidden)
 		return
 	}
-	accessToken, err := auth.MakeJWT(refreshToken.UserID, tokenSecret, time.Hour)
+	//tokens keep the scopes and client of the login that started the family
+	accessToken, err := Cfg.keys.MakeClientJWT(refreshToken.UserID, refreshToken.ClientID.String, refreshToken.Scopes, accessTokenTTL)
 	if err != nil {
-		log.Printf("Error generating access token: %v", err)
+		logErrorf(r.Context(), "Error generating access token: %v", err)
 		http.Error(w, `{"error":"Failed to generate access token"}`, http.StatusInternalServerError)
 		return
 	}
+	newToken, err := rotateRefreshToken(r.Context(), refreshToken, r.UserAgent(), clientIP(r))
+	if errors.Is(err, errRefreshTokenReused) {
+		revokeRefreshTokenFamily(r.Context(), refreshToken)
+		http.Error(w, `{"error":"Refresh token is revoked"}`, http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error rotating refresh token for user %q: %v", refreshToken.UserID, err)
+		http.Error(w, `{"error":"Failed to rotate refresh token"}`, http.StatusInternalServerError)
+		return
+	}
 	resp := struct {
-		AccessToken string `json:"token"`
+		AccessToken  string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: newToken,
 	}
 	jsonResp, err := json.Marshal(resp)
 	if err != nil {
-		log.Printf("Error marshaling response: %v", err)
+		logErrorf(r.Context(), "Error marshaling response: %v", err)
 		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
 		return
 	}
@@ -287,34 +507,74 @@ func login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding request: %v", err)
+		logErrorf(r.Context(), "Error decoding request: %v", err)
 		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
-	//verify usern and passw
+	//too many recent failures for this email or ip
+	keys := loginKeys(r, req.Email)
+	wait, err := loginBlockedFor(r.Context(), keys)
+	if err != nil {
+		logErrorf(r.Context(), "Error checking login failures: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeLoginBlocked(w, wait)
+		return
+	}
+	//verify usern and passw, unknown emails still pay for a bcrypt check
 	user, err := Cfg.db.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logErrorf(r.Context(), "Error fetching user for login: %v", err)
+		}
+		checkPasswordHash(r.Context(), dummyPasswordHash(), req.Password)
+		recordLoginFailure(r.Context(), keys, uuid.Nil)
+		failedLogins.Inc("password")
 		http.Error(w, `{"error":"Incorrect username or password"}`, http.StatusBadRequest)
+		return
 	}
-	err = auth.CheckPasswordHash(user.HashedPassword, req.Password)
+	err = checkPasswordHash(r.Context(), user.HashedPassword, req.Password)
 	if err != nil {
-
+		recordLoginFailure(r.Context(), keys, user.ID)
+		failedLogins.Inc("password")
 		http.Error(w, `{"error":"Incorrect username or password"}`, http.StatusBadRequest)
+		return
 	}
-	//make jwt
-	Token, err := auth.MakeJWT(user.ID, Cfg.secret, time.Hour)
+	completeLogin(w, r, user)
+}
+
+// completeLogin finishes a login once the user's first factor checked out.
+// Accounts with two-factor auth get a challenge instead of tokens.
+func completeLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	enabled, err := mfaEnabled(r.Context(), user.ID)
 	if err != nil {
-		log.Printf("Error generating JWT for user %q: %v", user.ID, err)
-		http.Error(w, `{"error":"Failed to generate access token"}`, http.StatusInternalServerError)
+		logErrorf(r.Context(), "Error checking mfa for user %q: %v", user.ID, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
-	refreshToken, _ := auth.MakeRefreshToken()
-	params := database.NewRefreshTokenParams{
-		Token:  refreshToken,
-		UserID: user.ID,
+	if enabled {
+		writeMFAChallenge(w, r, user)
+		return
 	}
-	Cfg.db.NewRefreshToken(r.Context(), params)
+	issueSession(w, r, user)
+}
+
+// issueSession writes the login response: a fresh access token and a new
+// refresh token for user. Failed logins against the account are forgotten
+// only here, once every factor has checked out.
+func issueSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	//make jwt and start a new refresh token family
+	Token, refreshToken, err := newTokenPair(r, user.ID, sql.NullString{}, auth.DefaultScopes)
+	if err != nil {
+		logErrorf(r.Context(), "Error generating tokens for user %q: %v", user.ID, err)
+		http.Error(w, `{"error":"Failed to generate access token"}`, http.StatusInternalServerError)
+		return
+	}
+	logins.Inc()
+	clearAccountFailures(r.Context(), loginKeys(r, user.Email))
 	resp := struct {
 		ID                uuid.UUID `json:"id"`
 		CreatedAt         time.Time `json:"created_at"`
@@ -342,20 +602,112 @@ func login(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonResp)
 }
 
+// yapResponse is the JSON shape of a yap returned by the read endpoints.
+type yapResponse struct {
+	ID             uuid.UUID            `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Body           string               `json:"body"`
+	UserID         uuid.UUID            `json:"user_id"`
+	ParentID       uuid.NullUUID        `json:"parent_id"`
+	ConversationID uuid.UUID            `json:"conversation_id"`
+	RepostOfID     uuid.NullUUID        `json:"repost_of_id"`
+	QuoteOfID      uuid.NullUUID        `json:"quote_of_id"`
+	RepostOf       *yapResponse         `json:"repost_of,omitempty"`
+	QuotedYap      *yapResponse         `json:"quoted_yap,omitempty"`
+	Attachments    []attachmentResponse `json:"attachments"`
+	Likes          int64                `json:"likes"`
+	LikedByMe      bool                 `json:"liked_by_me"`
+	Reposts        int64                `json:"reposts"`
+	Quotes         int64                `json:"quotes"`
+	RepostedByMe   bool                 `json:"reposted_by_me"`
+}
+
+func newYapResponse(yap database.Yap) yapResponse {
+	return yapResponse{
+		ID:             yap.ID,
+		CreatedAt:      yap.CreatedAt,
+		UpdatedAt:      yap.UpdatedAt,
+		Body:           yap.Body,
+		UserID:         yap.UserID,
+		ParentID:       yap.ParentID,
+		ConversationID: yap.ConversationID,
+		RepostOfID:     yap.RepostOfID,
+		QuoteOfID:      yap.QuoteOfID,
+	}
+}
+
+func newYapResponses(yaps []database.Yap) []yapResponse {
+	resp := make([]yapResponse, 0, len(yaps))
+	for _, yap := range yaps {
+		resp = append(resp, newYapResponse(yap))
+	}
+	return resp
+}
+
+// hydrateYaps embeds reposted and quoted yaps and fills in attachments and
+// engagement counts, for both the yaps and whatever they embed. viewer may be uuid.Nil
+// for anonymous requests.
+func hydrateYaps(ctx context.Context, viewer uuid.UUID, yaps []yapResponse) error {
+	embedded, err := fetchEmbedded(ctx, yaps)
+	if err != nil {
+		return err
+	}
+	//one round of stats queries covers everything on the page
+	all := append(append(make([]yapResponse, 0, len(yaps)+len(embedded)), yaps...), embedded...)
+	if err := withLikeStats(ctx, viewer, all); err != nil {
+		return err
+	}
+	if err := withRepostStats(ctx, viewer, all); err != nil {
+		return err
+	}
+	if err := withAttachments(ctx, all); err != nil {
+		return err
+	}
+	copy(yaps, all[:len(yaps)])
+	byID := make(map[uuid.UUID]*yapResponse, len(embedded))
+	for i := range all[len(yaps):] {
+		e := &all[len(yaps)+i]
+		byID[e.ID] = e
+	}
+	for i := range yaps {
+		if yaps[i].RepostOfID.Valid {
+			yaps[i].RepostOf = byID[yaps[i].RepostOfID.UUID]
+		}
+		if yaps[i].QuoteOfID.Valid {
+			yaps[i].QuotedYap = byID[yaps[i].QuoteOfID.UUID]
+		}
+	}
+	return nil
+}
+
 func getYap(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	id, err := uuid.Parse(r.URL.Query().Get("yapId"))
+	id, err := uuid.Parse(r.PathValue("yapId"))
 	if err != nil {
-		w.WriteHeader(http.StatusFailedDependency)
+		http.Error(w, `{"error":"Invalid yap id"}`, http.StatusBadRequest)
+		return
+	}
+	yap, err := Cfg.db.GetYapByID(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Yap not found"}`, http.StatusNotFound)
+		return
 	}
-	yap, err := Cfg.db.GetYapByID(r.Context(), uuid.UUID(id))
 	if err != nil {
-		w.WriteHeader(404)
-		w.Write([]byte(err.Error()))
+		logErrorf(r.Context(), "Error fetching yap %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to fetch yap"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := []yapResponse{newYapResponse(yap)}
+	if err := hydrateYaps(r.Context(), optionalUserID(r), resp); err != nil {
+		logErrorf(r.Context(), "Error hydrating yaps: %v", err)
+		http.Error(w, `{"error":"Failed to fetch yaps"}`, http.StatusInternalServerError)
+		return
 	}
-	yapJSON, err := json.Marshal(yap)
+	yapJSON, err := json.Marshal(resp[0])
 	if err != nil {
-		w.WriteHeader(http.StatusFailedDependency)
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(yapJSON)
@@ -364,26 +716,50 @@ func getYap(w http.ResponseWriter, r *http.Request) {
 func getYaps(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var yaps []database.Yap
-	id, err := uuid.Parse(r.URL.Query().Get("authorId"))
+	page, err := parsePageParams(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Could not parse uuid", http.StatusBadRequest)
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
+	//an empty authorId lists yaps from everyone
+	id := uuid.Nil
+	if authorId := r.URL.Query().Get("authorId"); authorId != "" {
+		id, err = uuid.Parse(authorId)
+		if err != nil {
+			http.Error(w, "Could not parse uuid", http.StatusBadRequest)
+			return
+		}
+	}
 	if id != uuid.Nil {
-		yaps, err = Cfg.db.GetYapsByAuthor(r.Context(), id)
+		yaps, err = Cfg.db.GetYapsByAuthor(r.Context(), database.GetYapsByAuthorParams{
+			UserID:          id,
+			CursorCreatedAt: page.CursorCreatedAt,
+			CursorID:        page.CursorID,
+			Limit:           page.fetchLimit(),
+		})
 		if err != nil {
 			w.WriteHeader(http.StatusBadGateway)
 			return
 		}
 	} else {
-		yaps, err = Cfg.db.GetAllYaps(r.Context())
+		yaps, err = Cfg.db.GetAllYaps(r.Context(), database.GetAllYapsParams{
+			CursorCreatedAt: page.CursorCreatedAt,
+			CursorID:        page.CursorID,
+			Limit:           page.fetchLimit(),
+		})
 		if err != nil {
 			w.WriteHeader(http.StatusBadGateway)
 			return
 		}
 	}
 
-	yapsJSON, err := json.Marshal(yaps)
+	resp := newYapPage(newYapResponses(yaps), page)
+	if err := hydrateYaps(r.Context(), optionalUserID(r), resp.Yaps); err != nil {
+		logErrorf(r.Context(), "Error hydrating yaps: %v", err)
+		http.Error(w, `{"error":"Failed to fetch yaps"}`, http.StatusInternalServerError)
+		return
+	}
+	yapsJSON, err := json.Marshal(resp)
 	if err != nil {
 		w.WriteHeader(http.StatusFailedDependency)
 	}
@@ -396,7 +772,9 @@ func resetDb(w http.ResponseWriter, r *http.Request) {
 	if Cfg.platform != "dev" {
 		w.WriteHeader(http.StatusForbidden)
 	}
-	Cfg.db.DeleteAllUsers(r.Context())
+	if err := Cfg.db.DeleteAllUsers(r.Context()); err != nil {
+		logErrorf(r.Context(), "Error deleting users: %v", err)
+	}
 }
 
 func newUser(w http.ResponseWriter, r *http.Request) {
@@ -407,7 +785,7 @@ func newUser(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding request: %v", err)
+		logErrorf(r.Context(), "Error decoding request: %v", err)
 		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
@@ -423,13 +801,13 @@ func newUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	//hash passw
-	hashedPass, err := auth.HashPassword(req.Password)
+	hashedPass, err := hashPassword(r.Context(), req.Password)
 	if err != nil {
 		http.Error(w, `{"error":"Faileed to hash password"}`, http.StatusFailedDependency)
 	}
 	//check if db is initialized
 	if Cfg.db == nil {
-		log.Println("Database not initialized")
+		logErrorf(r.Context(), "Database not initialized")
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
@@ -440,13 +818,32 @@ func newUser(w http.ResponseWriter, r *http.Request) {
 	}
 	user, err := Cfg.db.CreateUser(r.Context(), params)
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
+		logErrorf(r.Context(), "Error creating user: %v", err)
 		http.Error(w, `{"error":"Failed to create user"}`, http.StatusInternalServerError)
 		return
 	}
-	userJSON, err := json.Marshal(user)
+	//the account works right away, but can't post until the email is verified
+	if err := sendVerificationEmail(r.Context(), user); err != nil {
+		logErrorf(r.Context(), "Error sending verification email to user %q: %v", user.ID, err)
+	}
+	resp := struct {
+		ID                uuid.UUID `json:"id"`
+		CreatedAt         time.Time `json:"created_at"`
+		UpdatedAt         time.Time `json:"updated_at"`
+		Email             string    `json:"email"`
+		EmailVerified     bool      `json:"email_verified"`
+		Has_yappy_premium bool      `json:"has_yappy_premium"`
+	}{
+		ID:                user.ID,
+		CreatedAt:         user.CreatedAt,
+		UpdatedAt:         user.UpdatedAt,
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerifiedAt.Valid,
+		Has_yappy_premium: user.HasYappyPremium,
+	}
+	userJSON, err := json.Marshal(resp)
 	if err != nil {
-		log.Printf("Error marshalling user to JSON: %v", err)
+		logErrorf(r.Context(), "Error marshalling user to JSON: %v", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
@@ -454,56 +851,54 @@ func newUser(w http.ResponseWriter, r *http.Request) {
 	w.Write(userJSON)
 }
 
-func reset(w http.ResponseWriter, r *http.Request) {
-	Cfg.fileserverHits.Store(0)
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Fileserver hits reset to 0"))
-}
-
-func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.fileserverHits.Add(1)
-		next.ServeHTTP(w, r)
-	})
-}
-
 func yaps(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	//req struct
 	var req struct {
-		Body   string    `json:"body"`
-		UserId uuid.UUID `json:"user_id"`
+		Body      string        `json:"body"`
+		UserId    uuid.UUID     `json:"user_id"`
+		InReplyTo uuid.NullUUID `json:"in_reply_to"`
+		QuoteOf   uuid.NullUUID `json:"quote_of"`
 	}
 	//decode req
 	decoder := json.NewDecoder(r.Body)
 	defer r.Body.Close()
 	if err := decoder.Decode(&req); err != nil {
-		log.Printf("Error decoding request: %v", err)
+		logErrorf(r.Context(), "Error decoding request: %v", err)
 		w.WriteHeader(500)
 		return
 	}
 	//response struct
 	type returnValues struct {
-		Id        string    `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserId    string    `json:"user_id"`
-		Err       string    `json:"error"`
-		Valid     bool      `json:"valid"`
-	}
-	//get bearer token
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(http.StatusFailedDependency)
-	}
-	//validate token
-	user_id, err := auth.ValidateJWT(token, Cfg.secret)
+		Id             string    `json:"id"`
+		CreatedAt      time.Time `json:"created_at"`
+		UpdatedAt      time.Time `json:"updated_at"`
+		Body           string    `json:"body"`
+		UserId         string    `json:"user_id"`
+		ParentId       string    `json:"parent_id,omitempty"`
+		ConversationId string    `json:"conversation_id,omitempty"`
+		QuoteOfId      string    `json:"quote_of_id,omitempty"`
+		Err            string    `json:"error"`
+		Valid          bool      `json:"valid"`
+	}
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
 	if err != nil {
 		w.WriteHeader(http.StatusForbidden)
+		return
 	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	//yaps are always posted as the caller
+	user_id := claims.UserID
 	if user_id != req.UserId {
 		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	//only verified accounts can post
+	if !requireVerifiedEmail(w, r, user_id) {
+		return
 	}
 
 	//If body too long (>140) return error
@@ -513,7 +908,7 @@ func yaps(w http.ResponseWriter, r *http.Request) {
 		}
 		data, err := json.Marshal(respBody)
 		if err != nil {
-			log.Printf("Error marshalling JSON: %s", err)
+			logErrorf(r.Context(), "Error marshalling JSON: %s", err)
 			w.WriteHeader(500)
 			return
 		}
@@ -521,35 +916,99 @@ func yaps(w http.ResponseWriter, r *http.Request) {
 		w.Write(data)
 		return
 	}
-	//Clean profanities 1.0
-	cleaned_body := req.Body
-	cleaned_body = strings.Replace(cleaned_body, "Lol", "****", -1)
-	cleaned_body = strings.Replace(cleaned_body, "fortnite", "****", -1)
-	cleaned_body = strings.Replace(cleaned_body, "damn", "****", -1)
+	//replies must point at a yap that exists
+	if req.InReplyTo.Valid {
+		_, err := Cfg.db.GetYapByID(r.Context(), req.InReplyTo.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, `{"error":"Yap being replied to not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logErrorf(r.Context(), "Error fetching parent yap %q: %v", req.InReplyTo.UUID, err)
+			http.Error(w, `{"error":"Failed to create chirp"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	//quoted yaps must exist, quoting a repost quotes the original
+	if req.QuoteOf.Valid {
+		quoted, err := Cfg.db.GetYapByID(r.Context(), req.QuoteOf.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, `{"error":"Quoted yap not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logErrorf(r.Context(), "Error fetching quoted yap %q: %v", req.QuoteOf.UUID, err)
+			http.Error(w, `{"error":"Failed to create chirp"}`, http.StatusInternalServerError)
+			return
+		}
+		if quoted.RepostOfID.Valid {
+			req.QuoteOf.UUID = quoted.RepostOfID.UUID
+		}
+	}
+	//run the body through moderation before it is saved
+	moderated, err := Cfg.moderator.Moderate(r.Context(), req.Body)
+	if err != nil {
+		logErrorf(r.Context(), "Error moderating yap: %v", err)
+		http.Error(w, `{"error":"Failed to moderate yap"}`, http.StatusInternalServerError)
+		return
+	}
+	if moderated.Action == moderation.Reject {
+		respBody := returnValues{
+			Err: "Yap contains disallowed content",
+		}
+		data, err := json.Marshal(respBody)
+		if err != nil {
+			logErrorf(r.Context(), "Error marshalling JSON: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write(data)
+		return
+	}
 	//save chirp to db
 	params := database.NewYapParams{
-		Body:   cleaned_body,
-		UserID: req.UserId,
+		Body:      moderated.Text,
+		UserID:    user_id,
+		ParentID:  req.InReplyTo,
+		QuoteOfID: req.QuoteOf,
 	}
 	chirp, err := Cfg.db.NewYap(r.Context(), params)
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
+		logErrorf(r.Context(), "Error creating user: %v", err)
 		http.Error(w, `{"error":"Failed to create chirp"}`, http.StatusInternalServerError)
 		return
 	}
+	yapsCreated.Inc()
+	//flagged yaps are posted but queued for a human to look at
+	if moderated.Flagged {
+		if _, err := Cfg.db.FlagYap(r.Context(), database.FlagYapParams{
+			YapID:  chirp.ID,
+			Reason: flagReason(moderated.Matches),
+		}); err != nil {
+			logErrorf(r.Context(), "Error flagging yap %q: %v", chirp.ID, err)
+		}
+	}
 
 	respBody := returnValues{
-		Id:        chirp.ID.String(),
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID.String(),
-		Valid:     true,
+		Id:             chirp.ID.String(),
+		CreatedAt:      chirp.CreatedAt,
+		UpdatedAt:      chirp.UpdatedAt,
+		Body:           chirp.Body,
+		UserId:         chirp.UserID.String(),
+		ConversationId: chirp.ConversationID.String(),
+		Valid:          true,
+	}
+	if chirp.ParentID.Valid {
+		respBody.ParentId = chirp.ParentID.UUID.String()
+	}
+	if chirp.QuoteOfID.Valid {
+		respBody.QuoteOfId = chirp.QuoteOfID.UUID.String()
 	}
 	//marshal and send reponse on successful creation
 	data, err := json.Marshal(respBody)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		logErrorf(r.Context(), "Error marshalling JSON: %s", err)
 		w.WriteHeader(500)
 		return
 	}
@@ -557,29 +1016,13 @@ func yaps(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func metrics(w http.ResponseWriter, r *http.Request) {
-	hits := int(Cfg.fileserverHits.Load())
-	w.WriteHeader(200)
-	tmpl, err := template.ParseFiles("./metrics.html")
-	if err != nil {
-		fmt.Println("Error loading template:", err)
-		return
-	}
-	data := struct {
-		Count int
-	}{
-		Count: hits,
-	}
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	err = tmpl.Execute(w, data)
-	if err != nil {
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
-		return
+// flagReason lists the words that caused a yap to be flagged.
+func flagReason(matches []moderation.Rule) string {
+	var words []string
+	for _, m := range matches {
+		if m.Action == moderation.Flag {
+			words = append(words, m.Word)
+		}
 	}
-}
-
-func readiness(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(200)
-	w.Write([]byte(""))
+	return "matched: " + strings.Join(words, ", ")
 }
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/totp"
+	"github.com/google/uuid"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// mfaEnabled reports whether the user has confirmed a TOTP authenticator.
+func mfaEnabled(ctx context.Context, user_id uuid.UUID) (bool, error) {
+	secret, err := Cfg.db.GetUserTOTP(ctx, user_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return secret.ConfirmedAt.Valid, nil
+}
+
+// checkSecondFactor accepts either a current TOTP code or one of the user's
+// unused recovery codes. Both are burned on success so they can't be replayed.
+func checkSecondFactor(ctx context.Context, user_id uuid.UUID, code string) (bool, error) {
+	if len(code) == totp.Digits {
+		secret, err := Cfg.db.GetUserTOTP(ctx, user_id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		step, ok := totp.Validate(secret.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		rows, err := Cfg.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			UserID:       user_id,
+			LastUsedStep: sql.NullInt64{Int64: step, Valid: true},
+		})
+		return rows > 0, err
+	}
+	rows, err := Cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user_id,
+		CodeHash: auth.HashRecoveryCode(code),
+	})
+	return rows > 0, err
+}
+
+func writeMFAChallenge(w http.ResponseWriter, r *http.Request, user database.User) {
+	token, err := auth.MakeMFAToken(user.ID, Cfg.secret, mfaChallengeTTL)
+	if err != nil {
+		logErrorf(r.Context(), "Error generating mfa token for user %q: %v", user.ID, err)
+		http.Error(w, `{"error":"Failed to generate mfa token"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}{
+		MFARequired: true,
+		MFAToken:    token,
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+func loginMFA(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	req := struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	user_id, err := auth.ValidateMFAToken(req.MFAToken, Cfg.secret)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid or expired mfa token"}`, http.StatusUnauthorized)
+		return
+	}
+	user, err := Cfg.db.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching user %q: %v", user_id, err)
+		http.Error(w, `{"error":"User not found"}`, http.StatusUnauthorized)
+		return
+	}
+	//wrong codes count against the account like wrong passwords, otherwise
+	//one mfa token would be good for unlimited guesses
+	keys := loginKeys(r, user.Email)
+	wait, err := loginBlockedFor(r.Context(), keys)
+	if err != nil {
+		logErrorf(r.Context(), "Error checking login failures: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeLoginBlocked(w, wait)
+		return
+	}
+	ok, err := checkSecondFactor(r.Context(), user_id, req.Code)
+	if err != nil {
+		logErrorf(r.Context(), "Error checking second factor for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		recordLoginFailure(r.Context(), keys, user.ID)
+		failedLogins.Inc("mfa")
+		http.Error(w, `{"error":"Incorrect code"}`, http.StatusUnauthorized)
+		return
+	}
+	issueSession(w, r, user)
+}
+
+func enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	user, err := Cfg.db.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching user %q: %v", user_id, err)
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logErrorf(r.Context(), "Error generating totp secret: %v", err)
+		http.Error(w, `{"error":"Failed to start enrollment"}`, http.StatusInternalServerError)
+		return
+	}
+	//starting again replaces an unconfirmed secret but never a confirmed one
+	_, err = Cfg.db.NewPendingTOTP(r.Context(), database.NewPendingTOTPParams{
+		UserID: user_id,
+		Secret: secret,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Two-factor authentication is already enabled"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error saving totp secret for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to start enrollment"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}{
+		Secret:     secret,
+		OtpauthURI: totp.URI("Yappy", user.Email, secret),
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonResp)
+}
+
+func confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	req := struct {
+		Code string `json:"code"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	secret, err := Cfg.db.GetUserTOTP(r.Context(), user_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Start enrollment first"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching totp secret for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if secret.ConfirmedAt.Valid {
+		http.Error(w, `{"error":"Two-factor authentication is already enabled"}`, http.StatusConflict)
+		return
+	}
+	step, ok := totp.Validate(secret.Secret, req.Code, time.Now())
+	if !ok {
+		http.Error(w, `{"error":"Incorrect code"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		logErrorf(r.Context(), "Error generating recovery codes: %v", err)
+		http.Error(w, `{"error":"Failed to enable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := Cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		logErrorf(r.Context(), "Error starting transaction: %v", err)
+		http.Error(w, `{"error":"Failed to enable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := txQueries(tx)
+	rows, err := qtx.ConfirmTOTP(r.Context(), database.ConfirmTOTPParams{
+		UserID:       user_id,
+		LastUsedStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error confirming totp for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to enable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, `{"error":"Two-factor authentication is already enabled"}`, http.StatusConflict)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), user_id); err != nil {
+		logErrorf(r.Context(), "Error clearing recovery codes for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to enable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	for _, code := range codes {
+		err := qtx.NewRecoveryCode(r.Context(), database.NewRecoveryCodeParams{
+			CodeHash: auth.HashRecoveryCode(code),
+			UserID:   user_id,
+		})
+		if err != nil {
+			logErrorf(r.Context(), "Error saving recovery code for user %q: %v", user_id, err)
+			http.Error(w, `{"error":"Failed to enable two-factor authentication"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logErrorf(r.Context(), "Error committing totp for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to enable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	//recovery codes are only ever shown here, we keep just their hashes
+	resp := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+func disableTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	req := struct {
+		Code string `json:"code"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	//a stolen access token alone shouldn't be enough to turn 2fa off
+	ok, err := checkSecondFactor(r.Context(), user_id, req.Code)
+	if err != nil {
+		logErrorf(r.Context(), "Error checking second factor for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, `{"error":"Incorrect code"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	tx, err := Cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		logErrorf(r.Context(), "Error starting transaction: %v", err)
+		http.Error(w, `{"error":"Failed to disable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := txQueries(tx)
+	if err := qtx.DeleteTOTP(r.Context(), user_id); err != nil {
+		logErrorf(r.Context(), "Error deleting totp for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to disable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), user_id); err != nil {
+		logErrorf(r.Context(), "Error deleting recovery codes for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to disable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logErrorf(r.Context(), "Error committing totp removal for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to disable two-factor authentication"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,701 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxRedirectURIs    = 10
+	maxClientsPerOwner = 20
+)
+
+// oauthClientResponse is a registered app. The secret is only included in
+// the response to registration.
+type oauthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newOAuthClientResponse(client database.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// oauthError writes an RFC 6749 section 5.2 error response.
+func oauthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="yappy"`)
+	}
+	jsonResp, _ := json.Marshal(struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description,omitempty"`
+	}{code, description})
+	w.WriteHeader(status)
+	w.Write(jsonResp)
+}
+
+// validRedirectURI accepts absolute https URLs, or http ones pointing at
+// the loopback interface for apps in development.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" || u.User != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
+
+// parseScopes splits a space separated scope parameter and checks every
+// scope is in allowed. An empty parameter means all of allowed.
+func parseScopes(scope string, allowed []string) ([]string, bool) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return slices.Clone(allowed), true
+	}
+	for _, s := range scopes {
+		if !slices.Contains(allowed, s) {
+			return nil, false
+		}
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes), true
+}
+
+// authenticateClient identifies the OAuth client making a request to the
+// token, introspection or revocation endpoints, from HTTP Basic auth or the
+// client_id and client_secret form fields. Public clients only send their id.
+func authenticateClient(r *http.Request) (database.OauthClient, bool) {
+	id, secret, basic := r.BasicAuth()
+	if !basic {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id == "" {
+		return database.OauthClient{}, false
+	}
+	client, err := Cfg.db.GetOAuthClient(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logErrorf(r.Context(), "Error fetching oauth client %q: %v", id, err)
+		}
+		return database.OauthClient{}, false
+	}
+	if !client.SecretHash.Valid {
+		return client, secret == ""
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, false
+	}
+	return client, true
+}
+
+func registerOAuthClient(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	req := struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes       []string `json:"scopes"`
+		Confidential bool     `json:"confidential"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		http.Error(w, `{"error":"Name must be between 1 and 100 characters"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.RedirectURIs) == 0 || len(req.RedirectURIs) > maxRedirectURIs {
+		http.Error(w, `{"error":"Between 1 and 10 redirect URIs are required"}`, http.StatusBadRequest)
+		return
+	}
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri) {
+			http.Error(w, `{"error":"Redirect URIs must be absolute https URLs (or http on localhost) without a fragment"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	scopes, ok := parseScopes(strings.Join(req.Scopes, " "), auth.OAuthScopes)
+	if !ok {
+		http.Error(w, `{"error":"Apps can only have the read and write scopes"}`, http.StatusBadRequest)
+		return
+	}
+	existing, err := Cfg.db.ListOAuthClients(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error listing oauth clients for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to register app"}`, http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxClientsPerOwner {
+		http.Error(w, `{"error":"Too many apps, delete one first"}`, http.StatusConflict)
+		return
+	}
+	id, err := auth.MakeRefreshToken()
+	if err != nil {
+		logErrorf(r.Context(), "Error generating client id: %v", err)
+		http.Error(w, `{"error":"Failed to register app"}`, http.StatusInternalServerError)
+		return
+	}
+	id = id[:32]
+	var secret string
+	var secretHash sql.NullString
+	if req.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			logErrorf(r.Context(), "Error generating client secret: %v", err)
+			http.Error(w, `{"error":"Failed to register app"}`, http.StatusInternalServerError)
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+	client, err := Cfg.db.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		ID:           id,
+		OwnerID:      user_id,
+		Name:         req.Name,
+		SecretHash:   secretHash,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       scopes,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error saving oauth client for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to register app"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := newOAuthClientResponse(client)
+	resp.ClientSecret = secret
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonResp)
+}
+
+func listOAuthClients(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	clients, err := Cfg.db.ListOAuthClients(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error listing oauth clients for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to list apps"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := make([]oauthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		resp = append(resp, newOAuthClientResponse(client))
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+func deleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	id := r.PathValue("id")
+
+	tx, err := Cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		logErrorf(r.Context(), "Error starting transaction: %v", err)
+		http.Error(w, `{"error":"Failed to delete app"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := txQueries(tx)
+	rows, err := qtx.RevokeOAuthClient(r.Context(), database.RevokeOAuthClientParams{
+		ID:      id,
+		OwnerID: user_id,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error revoking oauth client %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to delete app"}`, http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, `{"error":"App not found"}`, http.StatusNotFound)
+		return
+	}
+	//every user's grant to the app goes with it
+	if err := qtx.RevokeRefreshTokensForClient(r.Context(), sql.NullString{String: id, Valid: true}); err != nil {
+		logErrorf(r.Context(), "Error revoking refresh tokens for oauth client %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to delete app"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logErrorf(r.Context(), "Error committing oauth client removal %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to delete app"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizationRequest is a validated RFC 6749 authorization request. PKCE
+// with S256 is required of every client.
+type authorizationRequest struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	Scopes        []string
+	State         string
+	CodeChallenge string
+}
+
+// parseAuthorizationRequest checks the client and redirect URI before
+// anything else. Until both are known good, errors must go back to the user
+// and never to the redirect URI.
+func parseAuthorizationRequest(r *http.Request, q url.Values) (authorizationRequest, string, error) {
+	client, err := Cfg.db.GetOAuthClient(r.Context(), q.Get("client_id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return authorizationRequest{}, "invalid_client", errors.New("unknown client_id")
+	}
+	if err != nil {
+		return authorizationRequest{}, "server_error", err
+	}
+	req := authorizationRequest{Client: client, RedirectURI: q.Get("redirect_uri"), State: q.Get("state")}
+	if !slices.Contains(client.RedirectUris, req.RedirectURI) {
+		return authorizationRequest{}, "invalid_request", errors.New("redirect_uri is not registered for this client")
+	}
+	if q.Get("response_type") != "code" {
+		return req, "unsupported_response_type", errors.New("response_type must be code")
+	}
+	scopes, ok := parseScopes(q.Get("scope"), client.Scopes)
+	if !ok {
+		return req, "invalid_scope", errors.New("scope is not allowed for this client")
+	}
+	req.Scopes = scopes
+	if q.Get("code_challenge_method") != "S256" || len(q.Get("code_challenge")) != 43 {
+		return req, "invalid_request", errors.New("an S256 code_challenge is required")
+	}
+	req.CodeChallenge = q.Get("code_challenge")
+	return req, "", nil
+}
+
+// redirectTo builds the URL the user agent is sent back to with either a
+// code or an error.
+func (req authorizationRequest) redirectTo(params url.Values) string {
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	u, _ := url.Parse(req.RedirectURI)
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// authorizeInfo describes an authorization request so a front end can show
+// the consent screen.
+func authorizeInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	req, code, err := parseAuthorizationRequest(r, r.URL.Query())
+	if err != nil {
+		if code == "server_error" {
+			logErrorf(r.Context(), "Error parsing authorization request: %v", err)
+		}
+		oauthError(w, http.StatusBadRequest, code, err.Error())
+		return
+	}
+	resp := struct {
+		ClientID    string   `json:"client_id"`
+		ClientName  string   `json:"client_name"`
+		RedirectURI string   `json:"redirect_uri"`
+		Scopes      []string `json:"scopes"`
+	}{
+		ClientID:    req.Client.ID,
+		ClientName:  req.Client.Name,
+		RedirectURI: req.RedirectURI,
+		Scopes:      req.Scopes,
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// authorize records the signed in user's answer on the consent screen and
+// returns where to send their browser next.
+func authorize(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	//apps can't grant other apps access
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	body := struct {
+		ClientID            string `json:"client_id"`
+		RedirectURI         string `json:"redirect_uri"`
+		ResponseType        string `json:"response_type"`
+		Scope               string `json:"scope"`
+		State               string `json:"state"`
+		CodeChallenge       string `json:"code_challenge"`
+		CodeChallengeMethod string `json:"code_challenge_method"`
+		Approve             bool   `json:"approve"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	req, code, err := parseAuthorizationRequest(r, url.Values{
+		"client_id":             {body.ClientID},
+		"redirect_uri":          {body.RedirectURI},
+		"response_type":         {body.ResponseType},
+		"scope":                 {body.Scope},
+		"state":                 {body.State},
+		"code_challenge":        {body.CodeChallenge},
+		"code_challenge_method": {body.CodeChallengeMethod},
+	})
+	var redirect string
+	switch {
+	case code == "server_error":
+		logErrorf(r.Context(), "Error parsing authorization request: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	case err != nil && req.RedirectURI == "":
+		oauthError(w, http.StatusBadRequest, code, err.Error())
+		return
+	case err != nil:
+		redirect = req.redirectTo(url.Values{"error": {code}, "error_description": {err.Error()}})
+	case !body.Approve:
+		redirect = req.redirectTo(url.Values{"error": {"access_denied"}})
+	default:
+		authCode, err := auth.MakeRefreshToken()
+		if err != nil {
+			logErrorf(r.Context(), "Error generating authorization code: %v", err)
+			http.Error(w, `{"error":"Failed to authorize app"}`, http.StatusInternalServerError)
+			return
+		}
+		err = Cfg.db.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+			CodeHash:      auth.HashToken(authCode),
+			ClientID:      req.Client.ID,
+			UserID:        user_id,
+			RedirectUri:   req.RedirectURI,
+			Scopes:        req.Scopes,
+			CodeChallenge: req.CodeChallenge,
+		})
+		if err != nil {
+			logErrorf(r.Context(), "Error saving authorization code for user %q: %v", user_id, err)
+			http.Error(w, `{"error":"Failed to authorize app"}`, http.StatusInternalServerError)
+			return
+		}
+		redirect = req.redirectTo(url.Values{"code": {authCode}})
+	}
+	jsonResp, err := json.Marshal(struct {
+		RedirectTo string `json:"redirect_to"`
+	}{redirect})
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// oauthToken is the RFC 6749 token endpoint. It takes form encoded
+// requests and supports the authorization_code and refresh_token grants.
+func oauthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "could not parse form body")
+		return
+	}
+	client, ok := authenticateClient(r)
+	if !ok {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+	var user_id uuid.UUID
+	var scopes []string
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code, err := Cfg.db.UseOAuthCode(r.Context(), auth.HashToken(r.PostForm.Get("code")))
+		if errors.Is(err, sql.ErrNoRows) {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "code is invalid, expired or already used")
+			return
+		}
+		if err != nil {
+			logErrorf(r.Context(), "Error using authorization code: %v", err)
+			oauthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		if code.ClientID != client.ID || code.RedirectUri != r.PostForm.Get("redirect_uri") {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "code was issued to another client or redirect_uri")
+			return
+		}
+		if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+			return
+		}
+		user_id = code.UserID
+		scopes = code.Scopes
+	case "refresh_token":
+		writeOAuthRefresh(w, r, client)
+		return
+	default:
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+		return
+	}
+	accessToken, refreshToken, err := newTokenPair(r, user_id, sql.NullString{String: client.ID, Valid: true}, scopes)
+	if err != nil {
+		logErrorf(r.Context(), "Error issuing tokens to oauth client %q: %v", client.ID, err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	writeOAuthTokens(w, accessToken, refreshToken, scopes)
+}
+
+// writeOAuthRefresh handles the refresh_token grant. Only the client a
+// token was issued to can use it, and it rotates just like /api/refresh.
+func writeOAuthRefresh(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	refreshToken, err := Cfg.db.GetRefreshToken(r.Context(), r.PostForm.Get("refresh_token"))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logErrorf(r.Context(), "Error fetching refresh token: %v", err)
+		}
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "refresh_token is invalid")
+		return
+	}
+	if refreshToken.ClientID.String != client.ID {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "refresh_token was issued to another client")
+		return
+	}
+	if refreshToken.ReplacedBy.Valid {
+		revokeRefreshTokenFamily(r.Context(), refreshToken)
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "refresh_token is revoked")
+		return
+	}
+	if refreshToken.RevokedAt.Valid || time.Now().After(refreshToken.ExpiresAt) {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "refresh_token is revoked or expired")
+		return
+	}
+	accessToken, err := Cfg.keys.MakeClientJWT(refreshToken.UserID, client.ID, refreshToken.Scopes, accessTokenTTL)
+	if err != nil {
+		logErrorf(r.Context(), "Error generating access token: %v", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	newToken, err := rotateRefreshToken(r.Context(), refreshToken, r.UserAgent(), clientIP(r))
+	if errors.Is(err, errRefreshTokenReused) {
+		revokeRefreshTokenFamily(r.Context(), refreshToken)
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "refresh_token is revoked")
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error rotating refresh token for user %q: %v", refreshToken.UserID, err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	writeOAuthTokens(w, accessToken, newToken, refreshToken.Scopes)
+}
+
+// writeOAuthTokens writes an RFC 6749 section 5.1 token response.
+func writeOAuthTokens(w http.ResponseWriter, accessToken, refreshToken string, scopes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	jsonResp, err := json.Marshal(struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	})
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// introspectResponse is an RFC 7662 introspection response. Inactive tokens
+// only ever get {"active":false}.
+type introspectResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// introspectToken is the RFC 7662 endpoint. Only confidential clients may
+// call it. Any valid access token is described, refresh tokens only to the
+// client that holds them.
+func introspectToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "could not parse form body")
+		return
+	}
+	client, ok := authenticateClient(r)
+	if !ok || !client.SecretHash.Valid {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "a confidential client is required")
+		return
+	}
+	token := r.PostForm.Get("token")
+	resp := introspectResponse{}
+	if claims, err := Cfg.keys.ValidateJWT(token); err == nil {
+		resp = introspectResponse{
+			Active:    true,
+			Scope:     strings.Join(claims.Scopes, " "),
+			ClientID:  claims.ClientID,
+			Subject:   claims.UserID.String(),
+			TokenType: "access_token",
+			IssuedAt:  claims.IssuedAt.Unix(),
+			ExpiresAt: claims.ExpiresAt.Unix(),
+		}
+	} else if refreshToken, err := Cfg.db.GetRefreshToken(r.Context(), token); err == nil &&
+		refreshToken.ClientID.String == client.ID &&
+		!refreshToken.RevokedAt.Valid && !refreshToken.ReplacedBy.Valid &&
+		time.Now().Before(refreshToken.ExpiresAt) {
+		resp = introspectResponse{
+			Active:    true,
+			Scope:     strings.Join(refreshToken.Scopes, " "),
+			ClientID:  client.ID,
+			Subject:   refreshToken.UserID.String(),
+			TokenType: "refresh_token",
+			IssuedAt:  refreshToken.CreatedAt.Unix(),
+			ExpiresAt: refreshToken.ExpiresAt.Unix(),
+		}
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// revokeOAuthToken is the RFC 7009 endpoint. Revoking a refresh token ends
+// the whole grant. Access tokens are short lived and can't be revoked, and
+// unknown tokens are not an error, so this always answers 200.
+func revokeOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "could not parse form body")
+		return
+	}
+	client, ok := authenticateClient(r)
+	if !ok {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+	refreshToken, err := Cfg.db.GetRefreshToken(r.Context(), r.PostForm.Get("token"))
+	if err == nil && refreshToken.ClientID.String == client.ID {
+		if err := Cfg.db.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID); err != nil {
+			logErrorf(r.Context(), "Error revoking refresh token family %q: %v", refreshToken.FamilyID, err)
+			oauthError(w, http.StatusServiceUnavailable, "server_error", "")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor joins the fields of a page position into an opaque string.
+func encodeCursor(parts ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, "|")))
+}
+
+// decodeCursor splits a cursor back into exactly n fields.
+func decodeCursor(s string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != n {
+		return nil, errInvalidCursor
+	}
+	return parts, nil
+}
+
+// cursor is the position of the last yap on a page. It is handed to clients
+// as an opaque string and only ever compared against (created_at, id).
+type cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c cursor) encode() string {
+	return encodeCursor(c.CreatedAt.UTC().Format(time.RFC3339Nano), c.ID.String())
+}
+
+func decodeTimeCursor(s string) (cursor, error) {
+	parts, err := decodeCursor(s, 2)
+	if err != nil {
+		return cursor{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return cursor{}, errInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return cursor{}, errInvalidCursor
+	}
+	return cursor{CreatedAt: t, ID: id}, nil
+}
+
+// rankCursor is the search equivalent of cursor. Search results are ordered
+// by relevance, so the position is (rank, id) instead of (created_at, id).
+type rankCursor struct {
+	Rank float32
+	ID   uuid.UUID
+}
+
+func (c rankCursor) encode() string {
+	return encodeCursor(strconv.FormatFloat(float64(c.Rank), 'g', -1, 32), c.ID.String())
+}
+
+func decodeRankCursor(s string) (rankCursor, error) {
+	parts, err := decodeCursor(s, 2)
+	if err != nil {
+		return rankCursor{}, err
+	}
+	r, err := strconv.ParseFloat(parts[0], 32)
+	if err != nil {
+		return rankCursor{}, errInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return rankCursor{}, errInvalidCursor
+	}
+	return rankCursor{Rank: float32(r), ID: id}, nil
+}
+
+// threadCursor walks the replies under a yap breadth first, so the position
+// also carries how deep in the tree the last reply was.
+type threadCursor struct {
+	Depth     int32
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c threadCursor) encode() string {
+	return encodeCursor(strconv.Itoa(int(c.Depth)), c.CreatedAt.UTC().Format(time.RFC3339Nano), c.ID.String())
+}
+
+func decodeThreadCursor(s string) (threadCursor, error) {
+	parts, err := decodeCursor(s, 3)
+	if err != nil {
+		return threadCursor{}, err
+	}
+	depth, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return threadCursor{}, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return threadCursor{}, errInvalidCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return threadCursor{}, errInvalidCursor
+	}
+	return threadCursor{Depth: int32(depth), CreatedAt: t, ID: id}, nil
+}
+
+// pageParams holds the parsed "cursor" and "limit" query parameters. The
+// cursor fields are null on the first page.
+type pageParams struct {
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func parseLimit(q url.Values) (int32, error) {
+	l := q.Get("limit")
+	if l == "" {
+		return defaultPageLimit, nil
+	}
+	n, err := strconv.Atoi(l)
+	if err != nil || n < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return int32(min(n, maxPageLimit)), nil
+}
+
+func parsePageParams(q url.Values) (pageParams, error) {
+	limit, err := parseLimit(q)
+	if err != nil {
+		return pageParams{}, err
+	}
+	p := pageParams{Limit: limit}
+	if c := q.Get("cursor"); c != "" {
+		cur, err := decodeTimeCursor(c)
+		if err != nil {
+			return pageParams{}, err
+		}
+		p.CursorCreatedAt = sql.NullTime{Time: cur.CreatedAt, Valid: true}
+		p.CursorID = uuid.NullUUID{UUID: cur.ID, Valid: true}
+	}
+	return p, nil
+}
+
+// searchPageParams is pageParams for search, keyed on rank.
+type searchPageParams struct {
+	CursorRank sql.NullFloat64
+	CursorID   uuid.NullUUID
+	Limit      int32
+}
+
+func parseSearchPageParams(q url.Values) (searchPageParams, error) {
+	limit, err := parseLimit(q)
+	if err != nil {
+		return searchPageParams{}, err
+	}
+	p := searchPageParams{Limit: limit}
+	if c := q.Get("cursor"); c != "" {
+		cur, err := decodeRankCursor(c)
+		if err != nil {
+			return searchPageParams{}, err
+		}
+		p.CursorRank = sql.NullFloat64{Float64: float64(cur.Rank), Valid: true}
+		p.CursorID = uuid.NullUUID{UUID: cur.ID, Valid: true}
+	}
+	return p, nil
+}
+
+// threadPageParams is pageParams for the replies under a yap.
+type threadPageParams struct {
+	CursorDepth     sql.NullInt32
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func parseThreadPageParams(q url.Values) (threadPageParams, error) {
+	limit, err := parseLimit(q)
+	if err != nil {
+		return threadPageParams{}, err
+	}
+	p := threadPageParams{Limit: limit}
+	if c := q.Get("cursor"); c != "" {
+		cur, err := decodeThreadCursor(c)
+		if err != nil {
+			return threadPageParams{}, err
+		}
+		p.CursorDepth = sql.NullInt32{Int32: cur.Depth, Valid: true}
+		p.CursorCreatedAt = sql.NullTime{Time: cur.CreatedAt, Valid: true}
+		p.CursorID = uuid.NullUUID{UUID: cur.ID, Valid: true}
+	}
+	return p, nil
+}
+
+// fetchLimit is one more than the page size so we can tell whether another
+// page exists without a separate count query.
+func (p pageParams) fetchLimit() int32 {
+	return p.Limit + 1
+}
+
+func (p searchPageParams) fetchLimit() int32 {
+	return p.Limit + 1
+}
+
+func (p threadPageParams) fetchLimit() int32 {
+	return p.Limit + 1
+}
+
+// yapPage is the response envelope for every yap listing endpoint.
+type yapPage struct {
+	Yaps       []yapResponse `json:"yaps"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// newYapPage trims the extra row fetched by fetchLimit and sets the next
+// cursor from the last yap on the page.
+func newYapPage(yaps []yapResponse, p pageParams) yapPage {
+	page := yapPage{Yaps: yaps}
+	if page.Yaps == nil {
+		page.Yaps = []yapResponse{}
+	}
+	if len(page.Yaps) > int(p.Limit) {
+		page.Yaps = page.Yaps[:p.Limit]
+		last := page.Yaps[len(page.Yaps)-1]
+		page.NextCursor = cursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+	return page
+}
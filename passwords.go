@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/mail"
+)
+
+// sendPasswordResetEmail issues a one hour, single-use reset token for the
+// account registered to email, if there is one.
+func sendPasswordResetEmail(ctx context.Context, email string) error {
+	user, err := Cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, hash, err := auth.MakePasswordResetToken()
+	if err != nil {
+		return err
+	}
+	err = Cfg.db.NewPasswordResetToken(ctx, database.NewPasswordResetTokenParams{
+		TokenHash: hash,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return Cfg.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Yappy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Yappy account.\n\n"+
+			"Send this token with your new password to POST %s/api/password/reset:\n\n%s\n\n"+
+			"The token expires in 1 hour. If this wasn't you, you can ignore this email.\n", Cfg.baseURL, token),
+	})
+}
+
+func forgotPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	req := struct {
+		Email string `json:"email"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	if req.Email == "" {
+		http.Error(w, `{"error":"Email is required"}`, http.StatusBadRequest)
+		return
+	}
+	//do the work after responding so unknown and known emails look the same
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), backgroundTimeout)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		defer cancel()
+		if err := sendPasswordResetEmail(ctx, req.Email); err != nil {
+			logErrorf(ctx, "Error sending password reset email: %v", err)
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func resetPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	req := struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logErrorf(r.Context(), "Error decoding request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, `{"error":"Token and password are required"}`, http.StatusBadRequest)
+		return
+	}
+	hashedPassword, err := hashPassword(r.Context(), req.Password)
+	if err != nil {
+		logErrorf(r.Context(), "Error hashing password: %v", err)
+		http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := Cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		logErrorf(r.Context(), "Error starting transaction: %v", err)
+		http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := txQueries(tx)
+	//burn the token first so two concurrent resets can't both use it
+	reset, err := qtx.UsePasswordResetToken(r.Context(), auth.HashToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Invalid or expired reset token"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error using password reset token: %v", err)
+		http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             reset.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error updating password for user %q: %v", reset.UserID, err)
+		http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	//any other outstanding reset links and every session die with the old password
+	if err := qtx.InvalidatePasswordResetTokens(r.Context(), reset.UserID); err != nil {
+		logErrorf(r.Context(), "Error invalidating reset tokens for user %q: %v", reset.UserID, err)
+		http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := qtx.RevokeAllRefreshTokensForUser(r.Context(), reset.UserID); err != nil {
+		logErrorf(r.Context(), "Error revoking refresh tokens for user %q: %v", reset.UserID, err)
+		http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logErrorf(r.Context(), "Error committing password reset for user %q: %v", reset.UserID, err)
+		http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/metrics"
+)
+
+// unmatchedRoute labels requests no route matched, so junk paths can't
+// blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+var (
+	registry = metrics.NewRegistry()
+
+	httpRequests = registry.NewCounter("yappy_http_requests_total",
+		"HTTP requests served, by method, route pattern and status code.", "method", "route", "code")
+	httpDuration = registry.NewHistogram("yappy_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by method and route pattern.", metrics.DefaultBuckets, "method", "route")
+	httpInFlight = registry.NewGauge("yappy_http_requests_in_flight",
+		"HTTP requests currently being served.")
+
+	yapsCreated = registry.NewCounter("yappy_yaps_created_total",
+		"Yaps posted.")
+	logins = registry.NewCounter("yappy_logins_total",
+		"Successful logins that were issued tokens.")
+	failedLogins = registry.NewCounter("yappy_failed_logins_total",
+		"Failed logins, by reason: password, mfa or throttled.", "reason")
+)
+
+func init() {
+	registry.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// registerDBMetrics exposes the connection pool stats of db.
+func registerDBMetrics(db *sql.DB) {
+	registry.NewGaugeFunc("yappy_db_max_open_connections", "Maximum number of open connections to the database.", func() float64 {
+		return float64(db.Stats().MaxOpenConnections)
+	})
+	registry.NewGaugeFunc("yappy_db_open_connections", "Established connections to the database, in use or idle.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	registry.NewGaugeFunc("yappy_db_in_use_connections", "Database connections currently in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	registry.NewGaugeFunc("yappy_db_idle_connections", "Idle database connections.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	registry.NewCounterFunc("yappy_db_wait_count_total", "Times a query had to wait for a free database connection.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	registry.NewCounterFunc("yappy_db_wait_duration_seconds_total", "Time spent waiting for a free database connection.", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+}
+
+// statusRecorder remembers the status code and body size a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *statusRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// middlewareMetrics counts and times every request by the mux route it
+// matches. It goes outside everything else so throttled requests count.
+func (cfg *apiConfig) middlewareMetrics(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, r := cfg.withRequestInfo(mux, r)
+		method, route := r.Method, info.route
+		if route == "" {
+			method, route = "", unmatchedRoute
+		}
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		httpDuration.Observe(time.Since(start).Seconds(), method, route)
+		httpRequests.Inc(method, route, strconv.Itoa(rec.Status()))
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/ratelimit"
+)
+
+// defaultRateLimit is the key in rate limits for every route not listed.
+const defaultRateLimit = "default"
+
+// defaultRateLimits are keyed by route pattern. Anything that sends mail,
+// checks a password or creates content gets a tighter limit.
+var defaultRateLimits = map[string]string{
+	defaultRateLimit:                     "300/1m",
+	"POST /api/login":                    "10/1m",
+	"POST /api/login/mfa":                "10/1m",
+	"POST /api/users":                    "10/1h",
+	"POST /api/password/forgot":          "5/1h",
+	"POST /api/password/reset":           "10/1h",
+	"POST /api/users/verify/resend":      "5/1h",
+	"POST /api/yaps":                     "30/1m",
+	"POST /api/yaps/{yapId}/attachments": "30/1h",
+	"POST /api/oauth/token":              "60/1m",
+	//probes come from one address, often
+	"GET /livez":  "off",
+	"GET /readyz": "off",
+}
+
+// loadRateLimits applies RATE_LIMITS on top of the defaults. It is a
+// semicolon separated list like "POST /api/yaps=60/1m;default=off".
+func loadRateLimits(config string) (map[string]ratelimit.Limit, error) {
+	raw := make(map[string]string, len(defaultRateLimits))
+	for pattern, limit := range defaultRateLimits {
+		raw[pattern] = limit
+	}
+	for _, entry := range strings.Split(config, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		pattern, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q must look like pattern=30/1m", entry)
+		}
+		raw[strings.TrimSpace(pattern)] = limit
+	}
+	limits := make(map[string]ratelimit.Limit, len(raw))
+	for pattern, s := range raw {
+		limit, err := ratelimit.ParseLimit(s)
+		if err != nil {
+			return nil, err
+		}
+		limits[pattern] = limit
+	}
+	return limits, nil
+}
+
+// rateLimitKey identifies who a request is counted against: the user when
+// it carries a valid access token, otherwise the client IP. API keys count
+// against the IP, since checking them needs the database.
+func rateLimitKey(r *http.Request, info *requestInfo) string {
+	if info.claims != nil {
+		return "user:" + info.claims.UserID.String()
+	}
+	return "ip:" + clientIP(r)
+}
+
+// middlewareRateLimit applies the limit for whichever of mux's routes a
+// request matches, with a token bucket per route and user or IP. Responses
+// carry RateLimit-* headers, and refused requests get 429 and Retry-After.
+// If the store fails, requests are let through.
+func (cfg *apiConfig) middlewareRateLimit(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, r := cfg.withRequestInfo(mux, r)
+		pattern := info.route
+		limit, ok := cfg.rateLimits[pattern]
+		if !ok {
+			limit = cfg.rateLimits[defaultRateLimit]
+		}
+		if limit.Unlimited() {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		res, err := cfg.limiter.Take(r.Context(), pattern+" "+rateLimitKey(r, info), limit)
+		if err != nil {
+			logErrorf(r.Context(), "Error checking rate limit: %v", err)
+			mux.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Per)))
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"Too many requests, slow down"}`, http.StatusTooManyRequests)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// pruneRateLimits deletes idle buckets from the postgres store every
+// interval until ctx is done.
+func pruneRateLimits(ctx context.Context, store *ratelimit.Postgres, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := store.Prune(ctx, 24*time.Hour); err != nil {
+				logErrorf(ctx, "Error pruning rate limit buckets: %v", err)
+			}
+		}
+	}
+}
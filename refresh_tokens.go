@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+// accessTokenTTL is how long access tokens are valid for. Refresh tokens
+// last 60 days, see NewRefreshToken.
+const accessTokenTTL = time.Hour
+
+// errRefreshTokenReused means another request rotated the token first.
+var errRefreshTokenReused = errors.New("refresh token already rotated")
+
+// newTokenPair issues an access token and the first refresh token of a new
+// family, used by password login and the OAuth code exchange alike.
+func newTokenPair(r *http.Request, user_id uuid.UUID, clientID sql.NullString, scopes []string) (string, string, error) {
+	accessToken, err := Cfg.keys.MakeClientJWT(user_id, clientID.String, scopes, accessTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	_, err = Cfg.db.NewRefreshToken(r.Context(), database.NewRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    user_id,
+		FamilyID:  uuid.New(),
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+		ClientID:  clientID,
+		Scopes:    scopes,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// rotateRefreshToken swaps old for a new token in the same family. Each
+// refresh token can be exchanged exactly once. The new token records the
+// device that used it.
+func rotateRefreshToken(ctx context.Context, old database.RefreshToken, userAgent, ip string) (string, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	tx, err := Cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	qtx := txQueries(tx)
+	_, err = qtx.NewRefreshToken(ctx, database.NewRefreshTokenParams{
+		Token:     token,
+		UserID:    old.UserID,
+		FamilyID:  old.FamilyID,
+		UserAgent: userAgent,
+		IpAddress: ip,
+		ClientID:  old.ClientID,
+		Scopes:    old.Scopes,
+	})
+	if err != nil {
+		return "", err
+	}
+	rows, err := qtx.RotateRefreshToken(ctx, database.RotateRefreshTokenParams{
+		Token:      old.Token,
+		ReplacedBy: sql.NullString{String: token, Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	if rows == 0 {
+		return "", errRefreshTokenReused
+	}
+	return token, tx.Commit()
+}
+
+// revokeRefreshTokenFamily kills every token descended from the same login
+// after a rotated token is replayed. Either the legitimate client or an
+// attacker is holding a stale copy and we can't tell which, so both lose it.
+func revokeRefreshTokenFamily(ctx context.Context, token database.RefreshToken) {
+	if err := Cfg.db.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		logErrorf(ctx, "Error revoking refresh token family %q: %v", token.FamilyID, err)
+	}
+	logSecurityEvent(ctx, token.UserID, "refresh_token_reuse",
+		fmt.Sprintf("rotated refresh token presented again, revoked token family %s", token.FamilyID))
+}
+
+// logSecurityEvent records something suspicious about an account, both in
+// the server log and in security_events for later review.
+func logSecurityEvent(ctx context.Context, user_id uuid.UUID, event, details string) {
+	logWarnf(ctx, "Security event %s for user %q: %s", event, user_id, details)
+	err := Cfg.db.NewSecurityEvent(ctx, database.NewSecurityEventParams{
+		UserID:  user_id,
+		Event:   event,
+		Details: details,
+	})
+	if err != nil {
+		logErrorf(ctx, "Error saving security event for user %q: %v", user_id, err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+func repostYap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	user_id := claims.UserID
+	yap_id, err := uuid.Parse(r.PathValue("yapId"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	yap, err := Cfg.db.GetYapByID(r.Context(), yap_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Yap not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to repost yap"}`, http.StatusInternalServerError)
+		return
+	}
+	//only verified accounts can post
+	if !requireVerifiedEmail(w, r, user_id) {
+		return
+	}
+	//reposting a repost amplifies the original
+	if yap.RepostOfID.Valid {
+		yap_id = yap.RepostOfID.UUID
+	}
+	//reposting twice is a no-op
+	params := database.NewRepostParams{
+		UserID:     user_id,
+		RepostOfID: yap_id,
+	}
+	if err := Cfg.db.NewRepost(r.Context(), params); err != nil {
+		logErrorf(r.Context(), "Error reposting yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to repost yap"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func unrepostYap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token or api key
+	claims, err := authenticate(r)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeWrite) {
+		return
+	}
+	user_id := claims.UserID
+	yap_id, err := uuid.Parse(r.PathValue("yapId"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	yap, err := Cfg.db.GetYapByID(r.Context(), yap_id)
+	//deleting a yap deletes its reposts, so there is nothing left to undo
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to remove repost"}`, http.StatusInternalServerError)
+		return
+	}
+	//reposts of a repost were saved against the original
+	if yap.RepostOfID.Valid {
+		yap_id = yap.RepostOfID.UUID
+	}
+	params := database.DeleteRepostParams{
+		UserID:     user_id,
+		RepostOfID: yap_id,
+	}
+	if err := Cfg.db.DeleteRepost(r.Context(), params); err != nil {
+		logErrorf(r.Context(), "Error removing repost of yap %q: %v", yap_id, err)
+		http.Error(w, `{"error":"Failed to remove repost"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withRepostStats fills in repost and quote counts and the reposted_by_me
+// flag for each yap with a single query.
+func withRepostStats(ctx context.Context, viewer uuid.UUID, yaps []yapResponse) error {
+	if len(yaps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(yaps))
+	for _, yap := range yaps {
+		ids = append(ids, yap.ID)
+	}
+	stats, err := Cfg.db.GetRepostStats(ctx, database.GetRepostStatsParams{
+		ViewerID: viewer,
+		YapIds:   ids,
+	})
+	if err != nil {
+		return err
+	}
+	byYap := make(map[uuid.UUID]database.GetRepostStatsRow, len(stats))
+	for _, s := range stats {
+		byYap[s.YapID] = s
+	}
+	for i := range yaps {
+		s := byYap[yaps[i].ID]
+		yaps[i].Reposts = s.RepostCount
+		yaps[i].Quotes = s.QuoteCount
+		yaps[i].RepostedByMe = s.RepostedByMe
+	}
+	return nil
+}
+
+// fetchEmbedded loads the yaps that reposts and quotes point at. Yaps whose
+// original has been deleted simply have nothing to embed: reposts are
+// removed along with the original and quotes lose their quote_of_id.
+func fetchEmbedded(ctx context.Context, yaps []yapResponse) ([]yapResponse, error) {
+	var ids []uuid.UUID
+	for _, yap := range yaps {
+		if yap.RepostOfID.Valid {
+			ids = append(ids, yap.RepostOfID.UUID)
+		}
+		if yap.QuoteOfID.Valid {
+			ids = append(ids, yap.QuoteOfID.UUID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	embedded, err := Cfg.db.GetYapsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return newYapResponses(embedded), nil
+}
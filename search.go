@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/search"
+)
+
+func searchYaps(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//turn the raw query into a tsquery
+	query, err := search.ParseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	page, err := parseSearchPageParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	rows, err := Cfg.db.SearchYaps(r.Context(), database.SearchYapsParams{
+		Query:      query,
+		CursorRank: page.CursorRank,
+		CursorID:   page.CursorID,
+		Limit:      page.fetchLimit(),
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error searching yaps for %q: %v", query, err)
+		http.Error(w, `{"error":"Failed to search yaps"}`, http.StatusInternalServerError)
+		return
+	}
+	//most relevant first, next cursor points at the last yap shown
+	resp := yapPage{Yaps: []yapResponse{}}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		resp.NextCursor = rankCursor{Rank: last.Rank, ID: last.ID}.encode()
+	}
+	for _, row := range rows {
+		resp.Yaps = append(resp.Yaps, yapResponse{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			Body:           row.Body,
+			UserID:         row.UserID,
+			ParentID:       row.ParentID,
+			ConversationID: row.ConversationID,
+			RepostOfID:     row.RepostOfID,
+			QuoteOfID:      row.QuoteOfID,
+		})
+	}
+	if err := hydrateYaps(r.Context(), optionalUserID(r), resp.Yaps); err != nil {
+		logErrorf(r.Context(), "Error hydrating yaps: %v", err)
+		http.Error(w, `{"error":"Failed to fetch yaps"}`, http.StatusInternalServerError)
+		return
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
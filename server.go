@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/health"
+)
+
+// serverConfig is the HTTP server's timeouts and limits, and how long it
+// takes over shutting down.
+type serverConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// DrainDelay is how long /readyz fails before the listener closes, so
+	// load balancers stop sending requests before they start being refused.
+	DrainDelay time.Duration
+	// ShutdownTimeout is how long in flight requests get to finish.
+	ShutdownTimeout time.Duration
+}
+
+// defaultServerConfig leaves room for a 5MB attachment on a slow
+// connection.
+var defaultServerConfig = serverConfig{
+	ReadHeaderTimeout: 10 * time.Second,
+	ReadTimeout:       2 * time.Minute,
+	WriteTimeout:      2 * time.Minute,
+	IdleTimeout:       2 * time.Minute,
+	MaxHeaderBytes:    64 << 10,
+	ShutdownTimeout:   30 * time.Second,
+}
+
+// loadServerConfig applies the HTTP_* and SHUTDOWN_* variables on top of
+// the defaults. Durations are Go durations like "30s".
+func loadServerConfig(getenv func(string) string) (serverConfig, error) {
+	cfg := defaultServerConfig
+	for name, d := range map[string]*time.Duration{
+		"HTTP_READ_HEADER_TIMEOUT": &cfg.ReadHeaderTimeout,
+		"HTTP_READ_TIMEOUT":        &cfg.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":       &cfg.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        &cfg.IdleTimeout,
+		"SHUTDOWN_DRAIN_DELAY":     &cfg.DrainDelay,
+		"SHUTDOWN_TIMEOUT":         &cfg.ShutdownTimeout,
+	} {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return serverConfig{}, fmt.Errorf("invalid %s %q", name, value)
+		}
+		*d = parsed
+	}
+	if value := getenv("HTTP_MAX_HEADER_BYTES"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return serverConfig{}, fmt.Errorf("invalid HTTP_MAX_HEADER_BYTES %q", value)
+		}
+		cfg.MaxHeaderBytes = n
+	}
+	return cfg, nil
+}
+
+func (cfg serverConfig) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// background tracks work that handlers leave running after they respond,
+// like sending emails, so shutdown can wait for it before closing the
+// database.
+var background sync.WaitGroup
+
+// backgroundTimeout bounds each piece of background work.
+const backgroundTimeout = 30 * time.Second
+
+// serve runs server on ln until ctx is done, then shuts down gracefully:
+// readiness flips to draining, and after the drain delay the listener
+// closes and in flight requests and background work get until the
+// shutdown timeout to finish. Whatever is still running then is cut off
+// and an error returned.
+func serve(ctx context.Context, server *http.Server, ln net.Listener, checker *health.Checker, cfg serverConfig) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(ln)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down", "drain_delay", cfg.DrainDelay.String(), "timeout", cfg.ShutdownTimeout.String())
+	checker.Drain()
+	time.Sleep(cfg.DrainDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("waiting for in flight requests: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	finished := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-shutdownCtx.Done():
+		return fmt.Errorf("waiting for background work: %w", shutdownCtx.Err())
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+// sessionResponse is one logged in device or authorized app. A session is a
+// refresh token family, so its id stays the same as the token rotates.
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	ClientID   string    `json:"client_id,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// clientIP is the address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func listSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	rows, err := Cfg.db.ListSessions(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error listing sessions for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to list sessions"}`, http.StatusInternalServerError)
+		return
+	}
+	sessions := make([]sessionResponse, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, sessionResponse{
+			ID:         row.FamilyID,
+			ClientID:   row.ClientID.String,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+			SignedInAt: row.SignedInAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+		})
+	}
+	jsonResp, err := json.Marshal(sessions)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+func revokeSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid session id"}`, http.StatusBadRequest)
+		return
+	}
+	//scoped to the caller so one user can't end another's sessions
+	rows, err := Cfg.db.RevokeSession(r.Context(), database.RevokeSessionParams{
+		FamilyID: id,
+		UserID:   user_id,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error revoking session %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to revoke session"}`, http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, `{"error":"Session not found"}`, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func revokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	if err := Cfg.db.RevokeAllRefreshTokensForUser(r.Context(), user_id); err != nil {
+		logErrorf(r.Context(), "Error revoking sessions for user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to revoke sessions"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,22 @@
+-- name: NewAttachment :one
+INSERT INTO attachments (id, created_at, yap_id, blob_key, content_type, size)
+VALUES (
+    gen_random_uuid (),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: CountAttachmentsForYap :one
+SELECT COUNT(*) FROM attachments WHERE yap_id = $1;
+
+-- name: GetAttachmentsForYaps :many
+SELECT * FROM attachments
+WHERE yap_id = ANY(sqlc.arg('yap_ids')::uuid[])
+ORDER BY created_at ASC, id ASC;
+
+-- name: GetAttachmentByBlobKey :one
+SELECT * FROM attachments WHERE blob_key = $1 LIMIT 1;
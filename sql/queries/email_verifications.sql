@@ -0,0 +1,29 @@
+-- name: NewEmailVerification :exec
+INSERT INTO email_verifications (id, created_at, user_id, email, expires_at, used_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    NULL
+);
+
+-- name: UseEmailVerification :one
+UPDATE email_verifications
+SET
+    used_at = NOW()
+WHERE
+    id = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING *;
+
+-- name: VerifyUserEmail :execrows
+UPDATE users
+SET
+    updated_at = NOW(),
+    email_verified_at = NOW()
+WHERE
+    id = $1
+    AND email = $2;
@@ -0,0 +1,23 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetTimeline :many
+SELECT yaps.* FROM yaps
+JOIN follows ON follows.followee_id = yaps.user_id
+WHERE
+    follows.follower_id = sqlc.arg('follower_id')
+    AND (
+        sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (yaps.created_at, yaps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+    )
+ORDER BY yaps.created_at DESC, yaps.id DESC
+LIMIT sqlc.arg('limit');
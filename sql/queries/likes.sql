@@ -0,0 +1,32 @@
+-- name: LikeYap :exec
+INSERT INTO likes (user_id, yap_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, yap_id) DO NOTHING;
+
+-- name: UnlikeYap :exec
+DELETE FROM likes WHERE user_id = $1 AND yap_id = $2;
+
+-- name: GetLikeStats :many
+SELECT
+    yap_id,
+    COUNT(*) AS like_count,
+    BOOL_OR(user_id = sqlc.arg('viewer_id'))::boolean AS liked_by_me
+FROM likes
+WHERE yap_id = ANY(sqlc.arg('yap_ids')::uuid[])
+GROUP BY yap_id;
+
+-- name: GetLikedYaps :many
+SELECT yaps.*, likes.created_at AS liked_at FROM yaps
+JOIN likes ON likes.yap_id = yaps.id
+WHERE
+    likes.user_id = sqlc.arg('user_id')
+    AND (
+        sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (likes.created_at, yaps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+    )
+ORDER BY likes.created_at DESC, yaps.id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,48 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures
+WHERE
+    kind = $1
+    AND key = $2;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (kind, key, failures, first_failed_at, last_failed_at, blocked_until)
+VALUES (
+    sqlc.arg('kind'),
+    sqlc.arg('key'),
+    1,
+    sqlc.arg('failed_at'),
+    sqlc.arg('failed_at'),
+    sqlc.arg('failed_at')
+)
+ON CONFLICT (kind, key) DO UPDATE
+SET
+    -- a quiet spell wipes the slate
+    failures = CASE WHEN login_failures.last_failed_at < sqlc.arg('reset_before') THEN 1 ELSE login_failures.failures + 1 END,
+    first_failed_at = CASE WHEN login_failures.last_failed_at < sqlc.arg('reset_before') THEN EXCLUDED.first_failed_at ELSE login_failures.first_failed_at END,
+    last_failed_at = EXCLUDED.last_failed_at
+RETURNING *;
+
+-- name: BlockLogin :exec
+UPDATE login_failures
+SET
+    blocked_until = $3
+WHERE
+    kind = $1
+    AND key = $2;
+
+-- name: ClearLoginFailures :execrows
+DELETE FROM login_failures
+WHERE
+    kind = $1
+    AND key = $2;
+
+-- name: ListLoginBlocks :many
+SELECT * FROM login_failures
+WHERE blocked_until > $1
+ORDER BY blocked_until DESC;
+
+-- name: DeleteStaleLoginFailures :exec
+DELETE FROM login_failures
+WHERE
+    last_failed_at < $1
+    AND blocked_until < NOW();
@@ -0,0 +1,64 @@
+-- name: NewPendingTOTP :one
+INSERT INTO user_totp (user_id, secret, created_at, confirmed_at, last_used_step)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NULL,
+    NULL
+)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    secret = EXCLUDED.secret,
+    created_at = EXCLUDED.created_at
+WHERE
+    user_totp.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: ConfirmTOTP :execrows
+UPDATE user_totp
+SET
+    confirmed_at = NOW(),
+    last_used_step = $2
+WHERE
+    user_id = $1
+    AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET
+    last_used_step = $2
+WHERE
+    user_id = $1
+    AND confirmed_at IS NOT NULL
+    AND (last_used_step IS NULL OR last_used_step < $2);
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: NewRecoveryCode :exec
+INSERT INTO recovery_codes (code_hash, user_id, created_at, used_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NULL
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET
+    used_at = NOW()
+WHERE
+    user_id = $1
+    AND code_hash = $2
+    AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
@@ -0,0 +1,10 @@
+-- name: FlagYap :one
+INSERT INTO moderation_flags (id, created_at, yap_id, reason, resolved_at)
+VALUES (
+    gen_random_uuid (),
+    NOW(),
+    $1,
+    $2,
+    NULL
+)
+RETURNING *;
@@ -0,0 +1,68 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, owner_id, name, secret_hash, redirect_uris, scopes, revoked_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NULL
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE
+    id = $1
+    AND revoked_at IS NULL;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+WHERE
+    owner_id = $1
+    AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokeOAuthClient :execrows
+UPDATE oauth_clients
+SET
+    revoked_at = NOW()
+WHERE
+    id = $1
+    AND owner_id = $2
+    AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensForClient :exec
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW()
+WHERE
+    client_id = $1
+    AND revoked_at IS NULL;
+
+-- name: CreateOAuthCode :exec
+INSERT INTO oauth_codes (code_hash, created_at, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, used_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW() + INTERVAL '10 minutes',
+    NULL
+);
+
+-- name: UseOAuthCode :one
+UPDATE oauth_codes
+SET
+    used_at = NOW()
+WHERE
+    code_hash = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING *;
@@ -0,0 +1,19 @@
+-- name: LockRateLimitBucket :one
+-- new buckets start full, the caller refills and takes from the row
+INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (key) DO UPDATE
+SET
+    key = EXCLUDED.key
+RETURNING key, tokens, updated_at, NOW()::timestamp AS now;
+
+-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets
+SET
+    tokens = $2,
+    updated_at = $3
+WHERE key = $1;
+
+-- name: DeleteIdleRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
@@ -0,0 +1,30 @@
+-- name: NewRepost :exec
+INSERT INTO yaps (id, created_at, updated_at, body, user_id, conversation_id, repost_of_id)
+SELECT
+    new_yap.id,
+    NOW(),
+    NOW(),
+    '',
+    sqlc.arg('user_id'),
+    new_yap.id,
+    sqlc.arg('repost_of_id')
+FROM (SELECT gen_random_uuid () AS id) AS new_yap
+ON CONFLICT (user_id, repost_of_id) WHERE repost_of_id IS NOT NULL DO NOTHING;
+
+-- name: DeleteRepost :exec
+DELETE FROM yaps WHERE user_id = $1 AND repost_of_id = $2;
+
+-- name: GetRepostStats :many
+SELECT
+    COALESCE(repost_of_id, quote_of_id)::uuid AS yap_id,
+    COUNT(repost_of_id) AS repost_count,
+    COUNT(quote_of_id) AS quote_count,
+    BOOL_OR(repost_of_id IS NOT NULL AND user_id = sqlc.arg('viewer_id'))::boolean AS reposted_by_me
+FROM yaps
+WHERE
+    repost_of_id = ANY(sqlc.arg('yap_ids')::uuid[])
+    OR quote_of_id = ANY(sqlc.arg('yap_ids')::uuid[])
+GROUP BY COALESCE(repost_of_id, quote_of_id);
+
+-- name: GetYapsByIDs :many
+SELECT * FROM yaps WHERE id = ANY(sqlc.arg('ids')::uuid[]);
@@ -0,0 +1,21 @@
+-- name: SearchYaps :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, conversation_id, repost_of_id, quote_of_id, rank FROM (
+    SELECT
+        yaps.id,
+        yaps.created_at,
+        yaps.updated_at,
+        yaps.body,
+        yaps.user_id,
+        yaps.parent_id,
+        yaps.conversation_id,
+        yaps.repost_of_id,
+        yaps.quote_of_id,
+        ts_rank(yaps.search, to_tsquery('english', sqlc.arg('query'))) AS rank
+    FROM yaps
+    WHERE yaps.search @@ to_tsquery('english', sqlc.arg('query'))
+) AS matches
+WHERE
+    sqlc.narg('cursor_rank')::real IS NULL
+    OR (rank, id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_id')::uuid)
+ORDER BY rank DESC, id DESC
+LIMIT sqlc.arg('limit');
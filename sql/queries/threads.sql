@@ -0,0 +1,30 @@
+-- name: GetThreadAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.parent_id, parent.conversation_id, parent.repost_of_id, parent.quote_of_id, 1 AS depth
+    FROM yaps AS child
+    JOIN yaps AS parent ON parent.id = child.parent_id
+    WHERE child.id = sqlc.arg('id')
+    UNION ALL
+    SELECT yaps.id, yaps.created_at, yaps.updated_at, yaps.body, yaps.user_id, yaps.parent_id, yaps.conversation_id, yaps.repost_of_id, yaps.quote_of_id, ancestors.depth + 1
+    FROM yaps
+    JOIN ancestors ON yaps.id = ancestors.parent_id
+)
+SELECT id, created_at, updated_at, body, user_id, parent_id, conversation_id, repost_of_id, quote_of_id FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetThreadDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT id, created_at, updated_at, body, user_id, parent_id, conversation_id, repost_of_id, quote_of_id, 1 AS depth
+    FROM yaps
+    WHERE yaps.parent_id = sqlc.arg('id')
+    UNION ALL
+    SELECT yaps.id, yaps.created_at, yaps.updated_at, yaps.body, yaps.user_id, yaps.parent_id, yaps.conversation_id, yaps.repost_of_id, yaps.quote_of_id, descendants.depth + 1
+    FROM yaps
+    JOIN descendants ON yaps.parent_id = descendants.id
+)
+SELECT id, created_at, updated_at, body, user_id, parent_id, conversation_id, repost_of_id, quote_of_id, depth FROM descendants
+WHERE
+    sqlc.narg('cursor_depth')::int IS NULL
+    OR (depth, created_at, id) > (sqlc.narg('cursor_depth')::int, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY depth ASC, created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,21 @@
+-- name: GetUserByIdentity :one
+SELECT users.* FROM users
+JOIN user_identities ON user_identities.user_id = users.id
+WHERE
+    user_identities.issuer = $1
+    AND user_identities.subject = $2;
+
+-- name: LinkIdentity :exec
+INSERT INTO user_identities (issuer, subject, created_at, user_id, email, last_login_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    $3,
+    $4,
+    NOW()
+)
+ON CONFLICT (issuer, subject) DO UPDATE
+SET
+    email = EXCLUDED.email,
+    last_login_at = NOW();
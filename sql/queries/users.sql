@@ -13,30 +13,49 @@ RETURNING *;
 DELETE FROM users;
 
 -- name: NewYap :one
-INSERT INTO yaps (id, created_at, updated_at, body, user_id)
-VALUES (
-    gen_random_uuid (),
+INSERT INTO yaps (id, created_at, updated_at, body, user_id, parent_id, conversation_id, quote_of_id)
+SELECT
+    new_yap.id,
     NOW(),
     NOW(),
-    $1,
-    $2 
-)
+    sqlc.arg('body'),
+    sqlc.arg('user_id'),
+    sqlc.narg('parent_id'),
+    -- replies join their parent's conversation, everything else starts one
+    COALESCE(
+        (SELECT parent.conversation_id FROM yaps AS parent WHERE parent.id = sqlc.narg('parent_id')),
+        new_yap.id
+    ),
+    sqlc.narg('quote_of_id')
+FROM (SELECT gen_random_uuid () AS id) AS new_yap
 RETURNING *;
 
 -- name: NewRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at, client_id, scopes)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
-    NOW() + INTERTVAL '60 days',
-    NULL
+    NOW() + INTERVAL '60 days',
+    NULL,
+    $3,
+    NULL,
+    $4,
+    $5,
+    NOW(),
+    $6,
+    $7
 )
 RETURNING *;
 
 -- name: GetAllYaps :many
-SELECT * FROM yaps ORDER BY created_at ASC;
+SELECT * FROM yaps
+WHERE
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
 
 -- name: GetYapByID :one
 SELECT * FROM yaps WHERE id = $1;
@@ -55,10 +74,102 @@ SET
 WHERE
     token = $1;
 
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW()
+WHERE
+    family_id = $1
+    AND user_id = $2
+    AND revoked_at IS NULL;
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW(),
+    replaced_by = $2
+WHERE
+    token = $1
+    AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW()
+WHERE
+    family_id = $1
+    AND revoked_at IS NULL;
+
+-- name: RevokeAllRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET
+    updated_at = NOW(),
+    revoked_at = NOW()
+WHERE
+    user_id = $1
+    AND revoked_at IS NULL;
+
+-- name: ListSessions :many
+SELECT
+    t.family_id,
+    t.user_agent,
+    t.ip_address,
+    t.last_used_at,
+    t.expires_at,
+    t.client_id,
+    (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id)::timestamp AS signed_in_at
+FROM refresh_tokens t
+WHERE
+    t.user_id = $1
+    AND t.revoked_at IS NULL
+    AND t.expires_at > NOW()
+ORDER BY t.last_used_at DESC;
+
+-- name: NewPasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, created_at, user_id, expires_at, used_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    NOW() + INTERVAL '1 hour',
+    NULL
+);
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET
+    used_at = NOW()
+WHERE
+    token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING *;
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET
+    used_at = NOW()
+WHERE
+    user_id = $1
+    AND used_at IS NULL;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+    updated_at = NOW(),
+    hashed_password = $2
+WHERE
+    id = $1;
+
 -- name: UpdateUser :exec
 UPDATE users
 SET
     updated_at = NOW(),
+    -- a new address has to be verified again
+    email_verified_at = CASE WHEN email = $1 THEN email_verified_at ELSE NULL END,
     email = $1,
     hashed_password= $2
 WHERE
@@ -75,4 +186,25 @@ WHERE
     id = $1;
 
 -- name: GetYapsByAuthor :many
-SELECT * FROM yaps WHERE user_id = $1 ORDER BY created_at ASC;
\ No newline at end of file
+SELECT * FROM yaps
+WHERE
+    user_id = sqlc.arg('user_id')
+    AND (
+        sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+    )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: NewSecurityEvent :exec
+INSERT INTO security_events (id, created_at, user_id, event, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+);
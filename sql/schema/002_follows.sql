@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+CREATE INDEX IF NOT EXISTS follows_followee_id_idx ON follows (followee_id);
+CREATE INDEX IF NOT EXISTS yaps_user_id_created_at_idx ON yaps (user_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS yaps_user_id_created_at_idx;
+DROP TABLE follows;
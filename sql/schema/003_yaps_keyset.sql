@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS yaps_created_at_id_idx ON yaps (created_at, id);
+DROP INDEX IF EXISTS yaps_user_id_created_at_idx;
+CREATE INDEX IF NOT EXISTS yaps_user_id_created_at_id_idx ON yaps (user_id, created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS yaps_user_id_created_at_id_idx;
+CREATE INDEX IF NOT EXISTS yaps_user_id_created_at_idx ON yaps (user_id, created_at);
+DROP INDEX IF EXISTS yaps_created_at_id_idx;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE yaps
+    ADD COLUMN IF NOT EXISTS search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+CREATE INDEX IF NOT EXISTS yaps_search_idx ON yaps USING GIN (search);
+
+-- +goose Down
+DROP INDEX IF EXISTS yaps_search_idx;
+ALTER TABLE yaps DROP COLUMN IF EXISTS search;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS moderation_flags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    yap_id UUID NOT NULL REFERENCES yaps(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    resolved_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS moderation_flags_unresolved_idx ON moderation_flags (created_at) WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE moderation_flags;
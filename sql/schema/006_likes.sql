@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS likes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    yap_id UUID NOT NULL REFERENCES yaps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, yap_id)
+);
+CREATE INDEX IF NOT EXISTS likes_yap_id_idx ON likes (yap_id);
+CREATE INDEX IF NOT EXISTS likes_user_id_created_at_idx ON likes (user_id, created_at);
+
+-- +goose Down
+DROP TABLE likes;
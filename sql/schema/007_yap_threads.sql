@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE yaps ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES yaps(id) ON DELETE SET NULL;
+ALTER TABLE yaps ADD COLUMN IF NOT EXISTS conversation_id UUID;
+UPDATE yaps SET conversation_id = id WHERE conversation_id IS NULL;
+ALTER TABLE yaps ALTER COLUMN conversation_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS yaps_parent_id_idx ON yaps (parent_id);
+CREATE INDEX IF NOT EXISTS yaps_conversation_id_idx ON yaps (conversation_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS yaps_conversation_id_idx;
+DROP INDEX IF EXISTS yaps_parent_id_idx;
+ALTER TABLE yaps DROP COLUMN IF EXISTS conversation_id;
+ALTER TABLE yaps DROP COLUMN IF EXISTS parent_id;
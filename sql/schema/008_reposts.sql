@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE yaps ADD COLUMN IF NOT EXISTS repost_of_id UUID REFERENCES yaps(id) ON DELETE CASCADE;
+ALTER TABLE yaps ADD COLUMN IF NOT EXISTS quote_of_id UUID REFERENCES yaps(id) ON DELETE SET NULL;
+ALTER TABLE yaps ADD CONSTRAINT yaps_repost_or_quote CHECK (repost_of_id IS NULL OR quote_of_id IS NULL);
+CREATE UNIQUE INDEX IF NOT EXISTS yaps_one_repost_per_user_idx ON yaps (user_id, repost_of_id) WHERE repost_of_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS yaps_repost_of_id_idx ON yaps (repost_of_id);
+CREATE INDEX IF NOT EXISTS yaps_quote_of_id_idx ON yaps (quote_of_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS yaps_quote_of_id_idx;
+DROP INDEX IF EXISTS yaps_repost_of_id_idx;
+DROP INDEX IF EXISTS yaps_one_repost_per_user_idx;
+ALTER TABLE yaps DROP CONSTRAINT IF EXISTS yaps_repost_or_quote;
+ALTER TABLE yaps DROP COLUMN IF EXISTS quote_of_id;
+ALTER TABLE yaps DROP COLUMN IF EXISTS repost_of_id;
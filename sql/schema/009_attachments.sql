@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    yap_id UUID NOT NULL REFERENCES yaps(id) ON DELETE CASCADE,
+    blob_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS attachments_yap_id_idx ON attachments (yap_id);
+CREATE INDEX IF NOT EXISTS attachments_blob_key_idx ON attachments (blob_key);
+
+-- +goose Down
+DROP TABLE attachments;
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/oidc"
+)
+
+const (
+	oidcLoginTTL    = 10 * time.Minute
+	oidcLoginCookie = "yappy_oidc_login"
+)
+
+// oidcLogin starts single sign-on by sending the user to the provider. The
+// state, nonce and PKCE verifier ride along in a signed cookie.
+func oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if Cfg.oidc == nil {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"Single sign-on is not configured"}`, http.StatusNotFound)
+		return
+	}
+	login, err := auth.NewOIDCLogin()
+	if err != nil {
+		logErrorf(r.Context(), "Error generating oidc login: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	token, err := auth.MakeOIDCLoginToken(login, Cfg.secret, oidcLoginTTL)
+	if err != nil {
+		logErrorf(r.Context(), "Error signing oidc login: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	setOIDCLoginCookie(w, token, int(oidcLoginTTL.Seconds()))
+	http.Redirect(w, r, Cfg.oidc.AuthCodeURL(login.State, login.Nonce, auth.S256Challenge(login.Verifier)), http.StatusFound)
+}
+
+// setOIDCLoginCookie sets, or with maxAge -1 clears, the cookie holding an
+// in progress login. It has to be Lax, not Strict, to survive the redirect
+// back from the provider.
+func setOIDCLoginCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     "/api/login/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(Cfg.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// oidcCallback is where the provider sends the user back. It checks the
+// state, redeems the code for an ID token and logs the matching user in.
+func oidcCallback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if Cfg.oidc == nil {
+		http.Error(w, `{"error":"Single sign-on is not configured"}`, http.StatusNotFound)
+		return
+	}
+	cookie, err := r.Cookie(oidcLoginCookie)
+	if err != nil {
+		http.Error(w, `{"error":"No sign-in in progress"}`, http.StatusBadRequest)
+		return
+	}
+	//each login attempt gets one try
+	setOIDCLoginCookie(w, "", -1)
+	login, err := auth.ValidateOIDCLoginToken(cookie.Value, Cfg.secret)
+	if err != nil {
+		http.Error(w, `{"error":"Sign-in expired, please try again"}`, http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(login.State)) != 1 {
+		http.Error(w, `{"error":"State does not match"}`, http.StatusBadRequest)
+		return
+	}
+	if q.Get("error") != "" {
+		http.Error(w, `{"error":"Sign-in was not completed"}`, http.StatusUnauthorized)
+		return
+	}
+	idToken, err := Cfg.oidc.Exchange(r.Context(), q.Get("code"), login.Verifier, login.Nonce)
+	if err != nil {
+		logErrorf(r.Context(), "Error exchanging oidc code: %v", err)
+		http.Error(w, `{"error":"Sign-in failed"}`, http.StatusUnauthorized)
+		return
+	}
+	if idToken.Email == "" || !idToken.EmailVerified {
+		http.Error(w, `{"error":"`+oidc.ErrEmailNotVerified.Error()+`"}`, http.StatusForbidden)
+		return
+	}
+	user, err := userForIdentity(r.Context(), idToken)
+	if err != nil {
+		logErrorf(r.Context(), "Error finding user for %s subject %q: %v", idToken.Issuer, idToken.Subject, err)
+		http.Error(w, `{"error":"Sign-in failed"}`, http.StatusInternalServerError)
+		return
+	}
+	completeLogin(w, r, user)
+}
+
+// userForIdentity finds the user an ID token belongs to. The first time an
+// identity is seen it is linked to the user with the same email, or a new
+// user is made for it. Either way the email counts as verified from then on.
+func userForIdentity(ctx context.Context, idToken oidc.IDToken) (database.User, error) {
+	identity := database.LinkIdentityParams{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		Email:   idToken.Email,
+	}
+	user, err := Cfg.db.GetUserByIdentity(ctx, database.GetUserByIdentityParams{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+	})
+	if err == nil {
+		identity.UserID = user.ID
+		return user, Cfg.db.LinkIdentity(ctx, identity)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+
+	//nobody can log in with this password, it only fills the column
+	password, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.User{}, err
+	}
+	hash, err := hashPassword(ctx, password)
+	if err != nil {
+		return database.User{}, err
+	}
+	tx, err := Cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+	qtx := txQueries(tx)
+	tookOver := false
+	user, err = qtx.GetUserByEmail(ctx, idToken.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		user, err = qtx.CreateUser(ctx, database.CreateUserParams{
+			Email:          idToken.Email,
+			HashedPassword: hash,
+		})
+		if err != nil {
+			return database.User{}, err
+		}
+	case err != nil:
+		return database.User{}, err
+	case !user.EmailVerifiedAt.Valid:
+		//whoever registered the address never proved they own it, so their
+		//password, sessions, api keys and second factor go
+		if err := qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{ID: user.ID, HashedPassword: hash}); err != nil {
+			return database.User{}, err
+		}
+		if err := qtx.RevokeAllRefreshTokensForUser(ctx, user.ID); err != nil {
+			return database.User{}, err
+		}
+		if err := qtx.RevokeAllAPIKeysForUser(ctx, user.ID); err != nil {
+			return database.User{}, err
+		}
+		if err := qtx.DeleteTOTP(ctx, user.ID); err != nil {
+			return database.User{}, err
+		}
+		if err := qtx.DeleteRecoveryCodes(ctx, user.ID); err != nil {
+			return database.User{}, err
+		}
+		tookOver = true
+	}
+	if !user.EmailVerifiedAt.Valid {
+		if _, err := qtx.VerifyUserEmail(ctx, database.VerifyUserEmailParams{ID: user.ID, Email: user.Email}); err != nil {
+			return database.User{}, err
+		}
+	}
+	identity.UserID = user.ID
+	if err := qtx.LinkIdentity(ctx, identity); err != nil {
+		return database.User{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return database.User{}, err
+	}
+	details := fmt.Sprintf("linked %s subject %q", idToken.Issuer, idToken.Subject)
+	if tookOver {
+		details += ", took over unverified account: reset password, revoked sessions and api keys, removed mfa"
+	}
+	logSecurityEvent(ctx, user.ID, "oidc_linked", details)
+	return user, nil
+}
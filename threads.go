@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/google/uuid"
+)
+
+// threadReply is a yap under the one being viewed. Depth is 1 for direct
+// replies, 2 for replies to those, and so on; clients rebuild the tree from
+// parent_id.
+type threadReply struct {
+	yapResponse
+	Depth int32 `json:"depth"`
+}
+
+func getThread(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, err := uuid.Parse(r.PathValue("yapId"))
+	if err != nil {
+		http.Error(w, `{"error":"Could not parse uuid"}`, http.StatusBadRequest)
+		return
+	}
+	page, err := parseThreadPageParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	yap, err := Cfg.db.GetYapByID(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Yap not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching yap %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to fetch thread"}`, http.StatusInternalServerError)
+		return
+	}
+	//everything above the yap, root first
+	ancestors, err := Cfg.db.GetThreadAncestors(r.Context(), id)
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching ancestors of yap %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to fetch thread"}`, http.StatusInternalServerError)
+		return
+	}
+	//one page of everything below it, breadth first
+	descendants, err := Cfg.db.GetThreadDescendants(r.Context(), database.GetThreadDescendantsParams{
+		ID:              id,
+		CursorDepth:     page.CursorDepth,
+		CursorCreatedAt: page.CursorCreatedAt,
+		CursorID:        page.CursorID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching replies to yap %q: %v", id, err)
+		http.Error(w, `{"error":"Failed to fetch thread"}`, http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Yap        yapResponse   `json:"yap"`
+		Ancestors  []yapResponse `json:"ancestors"`
+		Replies    []threadReply `json:"replies"`
+		NextCursor string        `json:"next_cursor,omitempty"`
+	}{
+		Yap:       newYapResponse(yap),
+		Ancestors: make([]yapResponse, 0, len(ancestors)),
+		Replies:   make([]threadReply, 0, len(descendants)),
+	}
+	if len(descendants) > int(page.Limit) {
+		descendants = descendants[:page.Limit]
+		last := descendants[len(descendants)-1]
+		resp.NextCursor = threadCursor{Depth: last.Depth, CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+	for _, a := range ancestors {
+		resp.Ancestors = append(resp.Ancestors, yapResponse{
+			ID:             a.ID,
+			CreatedAt:      a.CreatedAt,
+			UpdatedAt:      a.UpdatedAt,
+			Body:           a.Body,
+			UserID:         a.UserID,
+			ParentID:       a.ParentID,
+			ConversationID: a.ConversationID,
+			RepostOfID:     a.RepostOfID,
+			QuoteOfID:      a.QuoteOfID,
+		})
+	}
+	for _, d := range descendants {
+		resp.Replies = append(resp.Replies, threadReply{
+			yapResponse: yapResponse{
+				ID:             d.ID,
+				CreatedAt:      d.CreatedAt,
+				UpdatedAt:      d.UpdatedAt,
+				Body:           d.Body,
+				UserID:         d.UserID,
+				ParentID:       d.ParentID,
+				ConversationID: d.ConversationID,
+				RepostOfID:     d.RepostOfID,
+				QuoteOfID:      d.QuoteOfID,
+			},
+			Depth: d.Depth,
+		})
+	}
+	//hydrate the whole thread in one go
+	all := make([]yapResponse, 0, len(resp.Ancestors)+1+len(resp.Replies))
+	all = append(all, resp.Yap)
+	all = append(all, resp.Ancestors...)
+	for _, reply := range resp.Replies {
+		all = append(all, reply.yapResponse)
+	}
+	if err := hydrateYaps(r.Context(), optionalUserID(r), all); err != nil {
+		logErrorf(r.Context(), "Error hydrating yaps: %v", err)
+		http.Error(w, `{"error":"Failed to fetch yaps"}`, http.StatusInternalServerError)
+		return
+	}
+	resp.Yap = all[0]
+	copy(resp.Ancestors, all[1:1+len(resp.Ancestors)])
+	for i := range resp.Replies {
+		resp.Replies[i].yapResponse = all[1+len(resp.Ancestors)+i]
+	}
+	jsonResp, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
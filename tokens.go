@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+)
+
+// loadKeyring signs access tokens with the key in JWT_SIGNING_KEY and also
+// accepts tokens from the keys listed in JWT_PREVIOUS_KEYS. Without a signing
+// key tokens are HS256 with JWT_SECRET, as before. With one, JWT_SECRET only
+// verifies access tokens until JWT_ACCEPT_LEGACY_HS256_UNTIL, if that is set,
+// since anyone holding the secret could otherwise forge them forever.
+func loadKeyring() (*auth.Keyring, error) {
+	path := os.Getenv("JWT_SIGNING_KEY")
+	if path == "" {
+		key, err := auth.NewHMACKey(Cfg.secret)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewKeyring(key)
+	}
+	current, err := auth.LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+	var previous []*auth.Key
+	for _, path := range strings.Split(os.Getenv("JWT_PREVIOUS_KEYS"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := auth.LoadKey(path)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+	if until := os.Getenv("JWT_ACCEPT_LEGACY_HS256_UNTIL"); until != "" {
+		notAfter, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_ACCEPT_LEGACY_HS256_UNTIL %q, expected RFC 3339", until)
+		}
+		legacy, err := auth.NewHMACKey(Cfg.secret)
+		if err != nil {
+			return nil, err
+		}
+		legacy.NotAfter = notAfter
+		previous = append(previous, legacy)
+	}
+	return auth.NewKeyring(current, previous...)
+}
+
+func jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	jsonResp, err := json.Marshal(Cfg.keys.JWKS())
+	if err != nil {
+		logErrorf(r.Context(), "Error marshaling jwks: %v", err)
+		http.Error(w, `{"error":"Failed to create response"}`, http.StatusInternalServerError)
+		return
+	}
+	//verifiers cache this, keep it short so rotations are picked up quickly
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonResp)
+}
+
+// requireScope writes an error and returns false unless the access token
+// grants scope.
+func requireScope(w http.ResponseWriter, claims auth.Claims, scope string) bool {
+	if !claims.HasScope(scope) {
+		http.Error(w, `{"error":"Token is missing the `+scope+` scope"}`, http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// middlewareTracing runs every request in a server span named after the mux
+// route it matches, continuing the caller's trace if it sent a traceparent.
+// It goes outermost so the other middleware's logs carry the trace ID, and
+// so the requestInfo it works out is shared by the rest.
+func (cfg *apiConfig) middlewareTracing(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, r := cfg.withRequestInfo(mux, r)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := info.route
+		name := route
+		if route == "" {
+			name = unmatchedRoute
+		}
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+		}
+		//patterns are "METHOD /path", the attribute only wants the path
+		if _, path, ok := strings.Cut(route, " "); ok {
+			attrs = append(attrs, semconv.HTTPRoute(path))
+		}
+		ctx, span := tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.Status()))
+		if rec.Status() >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(rec.Status()))
+		}
+	})
+}
+
+// hashPassword is auth.HashPassword in a span, bcrypt being most of the
+// time taken by a signup.
+func hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "auth.HashPassword")
+	hash, err := auth.HashPassword(password)
+	tracing.End(span, err)
+	return hash, err
+}
+
+// checkPasswordHash is auth.CheckPasswordHash in a span. A wrong password
+// is recorded on the span but doesn't fail it.
+func checkPasswordHash(ctx context.Context, hash, password string) error {
+	_, span := tracing.Start(ctx, "auth.CheckPasswordHash")
+	defer span.End()
+	err := auth.CheckPasswordHash(hash, password)
+	span.SetAttributes(attribute.Bool("auth.password_match", err == nil))
+	return err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/F0RG-2142/chirpy-proj/internal/auth"
+	"github.com/F0RG-2142/chirpy-proj/internal/database"
+	"github.com/F0RG-2142/chirpy-proj/internal/mail"
+	"github.com/google/uuid"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+// sendVerificationEmail issues a single-use verification token for the
+// user's current address and mails them a link to redeem it.
+func sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, id, err := auth.MakeEmailVerificationToken(user.ID, user.Email, Cfg.secret, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	err = Cfg.db.NewEmailVerification(ctx, database.NewEmailVerificationParams{
+		ID:        id,
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+	link := Cfg.baseURL + "/api/users/verify?token=" + url.QueryEscape(token)
+	return Cfg.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Yappy email address",
+		Body: fmt.Sprintf("Welcome to Yappy!\n\nConfirm this is your address by opening the link below:\n\n%s\n\n"+
+			"The link expires in 24 hours. If you didn't sign up, you can ignore this email.\n", link),
+	})
+}
+
+// requireVerifiedEmail writes an error and returns false unless the user has
+// verified their email address.
+func requireVerifiedEmail(w http.ResponseWriter, r *http.Request, user_id uuid.UUID) bool {
+	user, err := Cfg.db.GetUserByID(r.Context(), user_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusUnauthorized)
+		return false
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return false
+	}
+	if !user.EmailVerifiedAt.Valid {
+		http.Error(w, `{"error":"Verify your email address before posting"}`, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func verifyEmail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	claims, err := auth.ValidateEmailVerificationToken(r.URL.Query().Get("token"), Cfg.secret)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid or expired verification link"}`, http.StatusBadRequest)
+		return
+	}
+	tx, err := Cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		logErrorf(r.Context(), "Error starting transaction: %v", err)
+		http.Error(w, `{"error":"Failed to verify email"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := txQueries(tx)
+	//burn the token so the link only works once
+	verification, err := qtx.UseEmailVerification(r.Context(), claims.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, `{"error":"Invalid or expired verification link"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logErrorf(r.Context(), "Error using verification %q: %v", claims.ID, err)
+		http.Error(w, `{"error":"Failed to verify email"}`, http.StatusInternalServerError)
+		return
+	}
+	//the address may have changed since the link was sent
+	rows, err := qtx.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if err != nil {
+		logErrorf(r.Context(), "Error verifying email for user %q: %v", verification.UserID, err)
+		http.Error(w, `{"error":"Failed to verify email"}`, http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, `{"error":"Invalid or expired verification link"}`, http.StatusBadRequest)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logErrorf(r.Context(), "Error committing email verification for user %q: %v", verification.UserID, err)
+		http.Error(w, `{"error":"Failed to verify email"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func resendVerification(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	//get and validate auth token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := Cfg.keys.ValidateJWT(token)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	if !requireScope(w, claims, auth.ScopeAccount) {
+		return
+	}
+	user_id := claims.UserID
+	user, err := Cfg.db.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		logErrorf(r.Context(), "Error fetching user %q: %v", user_id, err)
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		http.Error(w, `{"error":"Email is already verified"}`, http.StatusConflict)
+		return
+	}
+	if err := sendVerificationEmail(r.Context(), user); err != nil {
+		logErrorf(r.Context(), "Error sending verification email to user %q: %v", user_id, err)
+		http.Error(w, `{"error":"Failed to send verification email"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}